@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"backend/controllers"
+	"backend/db"
+	"backend/janitor"
+	"backend/model"
+)
+
+// adminTasks are the maintenance tasks of the admin command
+var adminTasks = []command{
+	{"sweep", "run one janitor sweep now", sweepTask},
+	{"reindex", "rebuild the lobby browser index from the games", reindexTask},
+	{"grant-admin", "give a user the admin claim", grantAdminTask},
+	{"revoke-admin", "remove the admin claim of a user", revokeAdminTask},
+}
+
+// admin runs a maintenance task against the stores of the configured environment
+func admin(args []string) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		adminUsage()
+		return
+	}
+
+	name, args := args[0], args[1:]
+	for _, task := range adminTasks {
+		if task.name == name {
+			task.run(args)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "backend admin: unknown task %q\n\n", name)
+	adminUsage()
+	os.Exit(2)
+}
+
+func adminUsage() {
+	fmt.Fprint(os.Stderr, "Usage: backend admin <task> [flags]\n\nTasks:\n")
+	for _, task := range adminTasks {
+		fmt.Fprintf(os.Stderr, "  %-13s %s\n", task.name, task.summary)
+	}
+}
+
+// sweepTask runs one janitor sweep with the configured timeouts, e.g. from a cron job when the
+// server's janitor is disabled or after changing the retention
+func sweepTask(args []string) {
+	cfg := setup(flag.NewFlagSet("backend admin sweep", flag.ExitOnError), args)
+	connectStores(cfg)
+	defer db.ClosePgConnection()
+	// Sweeping releases the lobby codes of the games it abandons
+	initLobbyCodes(cfg)
+
+	report, err := janitor.Sweep(context.Background(), db.DbClient, janitor.Config{
+		Interval:         cfg.Janitor.Interval.Duration,
+		LobbyIdleTimeout: cfg.Janitor.LobbyIdleTimeout.Duration,
+		GameIdleTimeout:  cfg.Janitor.GameIdleTimeout.Duration,
+		Retention:        cfg.Janitor.Retention.Duration,
+	}, time.Now())
+	if err != nil {
+		fatal("Sweep failed", err)
+	}
+	slog.Info("Sweep done", "abandoned", report.Abandoned, "archived", report.Archived, "evicted", report.Evicted, "invites", report.Invites)
+}
+
+// reindexTask makes the lobby browser index match the stored games
+func reindexTask(args []string) {
+	cfg := setup(flag.NewFlagSet("backend admin reindex", flag.ExitOnError), args)
+	connectStores(cfg)
+	defer db.ClosePgConnection()
+
+	ctx := context.Background()
+	var games map[string]*model.Game
+	if err := db.DbClient.NewRef("games").Get(ctx, &games); err != nil {
+		fatal("Failed to read games", err)
+	}
+	changed, err := controllers.ReconcileLobbyIndex(ctx, db.DbClient, games)
+	if err != nil {
+		fatal("Reindex failed", err)
+	}
+	slog.Info("Lobby index rebuilt", "changed", changed)
+}
+
+func grantAdminTask(args []string) {
+	setAdminClaim("grant-admin", args, true)
+}
+
+func revokeAdminTask(args []string) {
+	setAdminClaim("revoke-admin", args, false)
+}
+
+// setAdminClaim sets or removes the admin custom claim of a user, keeping their other claims. The
+// user gets it with their next ID token, within the hour.
+func setAdminClaim(task string, args []string, isAdmin bool) {
+	fs := flag.NewFlagSet("backend admin "+task, flag.ExitOnError)
+	uid := fs.String("uid", "", "Firebase UID of the user")
+	cfg := setup(fs, args)
+	if *uid == "" {
+		fatal("Invalid flag", errors.New("-uid is required"))
+	}
+	connectStores(cfg)
+	defer db.ClosePgConnection()
+
+	ctx := context.Background()
+	user, err := db.AuthClient.GetUser(ctx, *uid)
+	if err != nil {
+		fatal("Failed to get user", err)
+	}
+	claims := user.CustomClaims
+	if claims == nil {
+		claims = map[string]interface{}{}
+	}
+	if isAdmin {
+		claims["admin"] = true
+	} else {
+		delete(claims, "admin")
+	}
+	if err := db.AuthClient.SetCustomUserClaims(ctx, *uid, claims); err != nil {
+		fatal("Failed to set claims", err)
+	}
+	slog.Info("Admin claim updated", "uid", *uid, "admin", isAdmin)
+}
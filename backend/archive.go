@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"backend/archive"
+	"backend/db"
+)
+
+// exportGames writes games to an archive file
+func exportGames(args []string) {
+	fs := flag.NewFlagSet("backend export", flag.ExitOnError)
+	out := fs.String("o", "games.jsonl.gz", "archive file to write")
+	from := fs.String("from", "all", "games to export: games, archive or all")
+	status := fs.String("status", "", "only export games with this status, e.g. over")
+	cfg := setup(fs, args)
+
+	var nodes []string
+	switch *from {
+	case "all":
+	case "games":
+		nodes = []string{archive.Games}
+	case "archive":
+		nodes = []string{archive.Archived}
+	default:
+		fatal("Invalid flag", fmt.Errorf("-from must be games, archive or all, got %q", *from))
+	}
+
+	connectStores(cfg)
+	defer db.ClosePgConnection()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fatal("Failed to create the archive", err)
+	}
+	count, err := archive.Export(context.Background(), db.DbClient, f, archive.ExportOptions{Nodes: nodes, Status: *status})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fatal("Export failed", err)
+	}
+	slog.Info("Games exported", "count", count, "file", *out)
+}
+
+// importGames loads games from an archive file written by exportGames
+func importGames(args []string) {
+	fs := flag.NewFlagSet("backend import", flag.ExitOnError)
+	in := fs.String("i", "games.jsonl.gz", "archive file to read")
+	overwrite := fs.Bool("overwrite", false, "replace games that already exist")
+	dryRun := fs.Bool("dry-run", false, "read the archive and count the games without writing them")
+	cfg := setup(fs, args)
+
+	connectStores(cfg)
+	defer db.ClosePgConnection()
+	initLobbyCodes(cfg)
+
+	f, err := os.Open(*in)
+	if err != nil {
+		fatal("Failed to open the archive", err)
+	}
+	defer f.Close()
+
+	report, err := archive.Import(context.Background(), db.DbClient, f, archive.ImportOptions{Overwrite: *overwrite, DryRun: *dryRun})
+	if err != nil {
+		fatal("Import failed", err)
+	}
+	slog.Info("Games imported", "imported", report.Imported, "skipped", report.Skipped, "dryRun", *dryRun)
+}
@@ -0,0 +1,187 @@
+// Package archive exports games to and imports them from archive files, gzipped JSON with one game per
+// line, to move games between databases or keep them past the retention period.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"firebase.google.com/go/v4/db"
+
+	"backend/controllers"
+	"backend/model"
+)
+
+// RTDB nodes games are exported from and imported to
+const (
+	// Games holds the lobbies, running games and recently finished ones
+	Games = "games"
+	// Archived holds the finished games the janitor moved out of Games
+	Archived = "archive/games"
+)
+
+// Record is one game of an archive
+type Record struct {
+	// Node is where the game was exported from, and is imported to
+	Node string      `json:"node"`
+	ID   string      `json:"id"`
+	Game *model.Game `json:"game"`
+	// PasswordHash is the bcrypt hash of the lobby password, if the game has one
+	PasswordHash string `json:"passwordHash,omitempty"`
+}
+
+// ExportOptions selects the games to export
+type ExportOptions struct {
+	// Nodes are the nodes to export, Games and Archived if empty
+	Nodes []string
+	// Status only exports games with this status, such as over, if set
+	Status string
+}
+
+// Export writes the selected games to w and returns how many were written
+func Export(ctx context.Context, dbClient *db.Client, w io.Writer, opts ExportOptions) (int, error) {
+	nodes := opts.Nodes
+	if len(nodes) == 0 {
+		nodes = []string{Games, Archived}
+	}
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	count := 0
+	for _, node := range nodes {
+		var games map[string]*model.Game
+		if err := dbClient.NewRef(node).Get(ctx, &games); err != nil {
+			return count, fmt.Errorf("failed to read %s: %w", node, err)
+		}
+
+		// Sorted so the same games always give the same archive
+		ids := make([]string, 0, len(games))
+		for id := range games {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			game := games[id]
+			if game == nil || (opts.Status != "" && game.Status() != opts.Status) {
+				continue
+			}
+			record := &Record{Node: node, ID: id, Game: game}
+			if game.HasPassword {
+				hash, err := controllers.LobbyPasswordHash(ctx, dbClient, id)
+				if err != nil {
+					return count, fmt.Errorf("failed to read the lobby password of %s: %w", id, err)
+				}
+				record.PasswordHash = hash
+			}
+			if err := enc.Encode(record); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, zw.Close()
+}
+
+// ImportOptions controls how games are imported
+type ImportOptions struct {
+	// Overwrite replaces games that already exist instead of skipping them
+	Overwrite bool
+	// DryRun reads the archive without writing anything
+	DryRun bool
+}
+
+// ImportReport counts what an import did
+type ImportReport struct {
+	Imported int
+	Skipped  int
+}
+
+// Import writes the games of the archive read from r back to their node. Imported games that are not
+// over get their lobby code reserved and, if listed, an entry in the lobby browser, so
+// controllers.LobbyCodes must be set up.
+func Import(ctx context.Context, dbClient *db.Client, r io.Reader, opts ImportOptions) (ImportReport, error) {
+	var report ImportReport
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return report, fmt.Errorf("not an archive: %w", err)
+	}
+	defer zr.Close()
+
+	scanner := bufio.NewScanner(zr)
+	// Games with a long history make long lines
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		record := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return report, fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Game == nil || record.ID == "" || (record.Node != Games && record.Node != Archived) {
+			return report, fmt.Errorf("line %d: not a game record", line)
+		}
+
+		imported, err := importRecord(ctx, dbClient, record, opts)
+		if err != nil {
+			return report, fmt.Errorf("line %d: game %s: %w", line, record.ID, err)
+		}
+		if imported {
+			report.Imported++
+		} else {
+			report.Skipped++
+		}
+	}
+	return report, scanner.Err()
+}
+
+// importRecord writes one game, reporting whether it was imported or skipped
+func importRecord(ctx context.Context, dbClient *db.Client, record *Record, opts ImportOptions) (bool, error) {
+	ref := dbClient.NewRef(record.Node + "/" + record.ID)
+	if !opts.Overwrite {
+		var existing *model.Game
+		if err := ref.Get(ctx, &existing); err != nil {
+			return false, err
+		}
+		if existing != nil {
+			return false, nil
+		}
+	}
+	if opts.DryRun {
+		return true, nil
+	}
+
+	// Unfinished games hold their lobby code, so it is reserved before the game is written
+	active := record.Node == Games && !record.Game.GameOver
+	if active && record.Game.LobbyCode != "" {
+		ok, err := controllers.LobbyCodes.Adopt(ctx, record.Game.LobbyCode, record.ID)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, fmt.Errorf("lobby code %s is held by another game", record.Game.LobbyCode)
+		}
+	}
+
+	if err := ref.Set(ctx, record.Game); err != nil {
+		if active {
+			if rerr := controllers.LobbyCodes.Release(ctx, record.Game.LobbyCode, record.ID); rerr != nil {
+				err = errors.Join(err, rerr)
+			}
+		}
+		return false, err
+	}
+	if record.PasswordHash != "" {
+		if err := controllers.SetLobbyPasswordHash(ctx, dbClient, record.ID, record.PasswordHash); err != nil {
+			return false, err
+		}
+	}
+	if !active {
+		return true, nil
+	}
+	return true, controllers.UpdateLobbyIndex(ctx, dbClient, record.ID, record.Game)
+}
@@ -0,0 +1,209 @@
+// Package assets serves the frontend files embedded in the binary, with cache headers and gzip and
+// brotli variants compressed once when the server starts.
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gofiber/fiber/v2"
+)
+
+// IndexFile is served for the root path and, as the entry point of the single-page app, for the
+// paths of the app that are not files
+const IndexFile = "index.html"
+
+// Cache-Control values. Files with a content hash in their name never change, the others are
+// revalidated with their ETag.
+const (
+	cacheImmutable  = "public, max-age=31536000, immutable"
+	cacheRevalidate = "no-cache"
+)
+
+// minCompressSize is the size below which compressing a file does not pay for itself
+const minCompressSize = 512
+
+// hashedName matches names with a content hash added by the frontend build, e.g. app.3f9a1c2b.js
+var hashedName = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[^.]+$`)
+
+// compressible are the content types worth compressing, images and fonts already are
+var compressible = []string{"text/", "application/javascript", "application/json", "application/xml", "image/svg+xml", "application/wasm"}
+
+// asset is a file ready to be sent
+type asset struct {
+	contentType  string
+	cacheControl string
+	etag         string
+	body         []byte
+	// gzip and brotli hold the compressed variants, nil if they are not smaller
+	gzip   []byte
+	brotli []byte
+}
+
+// Assets are the files of a file system, loaded in memory
+type Assets struct {
+	files map[string]*asset
+}
+
+// New loads every file of fsys, compressing the ones worth it
+func New(fsys fs.FS) (*Assets, error) {
+	a := &Assets{files: map[string]*asset{}}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		a.files["/"+name], err = newAsset(name, body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func newAsset(name string, body []byte) (*asset, error) {
+	sum := sha256.Sum256(body)
+	f := &asset{
+		contentType:  mime.TypeByExtension(path.Ext(name)),
+		cacheControl: cacheRevalidate,
+		etag:         `"` + hex.EncodeToString(sum[:8]) + `"`,
+		body:         body,
+	}
+	if f.contentType == "" {
+		f.contentType = fiber.MIMEOctetStream
+	}
+	if hashedName.MatchString(name) {
+		f.cacheControl = cacheImmutable
+	}
+	if len(body) < minCompressSize || !isCompressible(f.contentType) {
+		return f, nil
+	}
+
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gz.Write(body); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() < len(body) {
+		f.gzip = bytes.Clone(buf.Bytes())
+	}
+
+	buf.Reset()
+	br := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	if _, err := br.Write(body); err != nil {
+		return nil, err
+	}
+	if err := br.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() < len(body) {
+		f.brotli = bytes.Clone(buf.Bytes())
+	}
+	return f, nil
+}
+
+func isCompressible(contentType string) bool {
+	for _, prefix := range compressible {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler serves the GET and HEAD requests for a file. Other paths without an extension get the index
+// file, so the app can route them, unless isAPI reports them as API paths. Everything else is passed
+// on to the next handler.
+func (a *Assets) Handler(isAPI func(path string) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+
+		p := c.Path()
+		if strings.HasSuffix(p, "/") {
+			p += IndexFile
+		}
+		f, ok := a.files[p]
+		if !ok {
+			if isAPI(c.Path()) || path.Ext(p) != "" {
+				return c.Next()
+			}
+			if f, ok = a.files["/"+IndexFile]; !ok {
+				return c.Next()
+			}
+		}
+		return f.send(c)
+	}
+}
+
+// send writes the file, or 304 if the client has it, choosing the smallest encoding it accepts
+func (f *asset) send(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, f.cacheControl)
+	c.Set(fiber.HeaderETag, f.etag)
+	c.Set(fiber.HeaderVary, fiber.HeaderAcceptEncoding)
+	if matchesETag(c.Get(fiber.HeaderIfNoneMatch), f.etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, f.contentType)
+	body := f.body
+	accepted := c.Get(fiber.HeaderAcceptEncoding)
+	switch {
+	case f.brotli != nil && acceptsEncoding(accepted, "br"):
+		c.Set(fiber.HeaderContentEncoding, "br")
+		body = f.brotli
+	case f.gzip != nil && acceptsEncoding(accepted, "gzip"):
+		c.Set(fiber.HeaderContentEncoding, "gzip")
+		body = f.gzip
+	}
+	return c.Send(body)
+}
+
+// matchesETag reports whether an If-None-Match header lists etag, also as a weak validator
+func matchesETag(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows encoding, which it does unless it is
+// missing or has a quality of 0
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) && strings.TrimSpace(name) != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if quality, err := strconv.ParseFloat(q, 64); err == nil && quality == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package assets
+
+import "testing"
+
+func TestMatchesETag(t *testing.T) {
+	const etag = `"abc123"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: `"abc123"`, want: true},
+		{header: `W/"abc123"`, want: true},
+		{header: `"other", "abc123"`, want: true},
+		{header: `"other"`, want: false},
+		{header: `abc123`, want: false},
+		{header: `*`, want: true},
+	}
+	for _, tt := range tests {
+		if got := matchesETag(tt.header, etag); got != tt.want {
+			t.Errorf("matchesETag(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header   string
+		encoding string
+		want     bool
+	}{
+		{header: "", encoding: "gzip", want: false},
+		{header: "gzip", encoding: "gzip", want: true},
+		{header: "gzip, deflate, br", encoding: "br", want: true},
+		{header: "GZIP", encoding: "gzip", want: true},
+		{header: "deflate", encoding: "gzip", want: false},
+		{header: "gzip;q=0.5", encoding: "gzip", want: true},
+		{header: "gzip;q=0", encoding: "gzip", want: false},
+		{header: "br;q=0, gzip", encoding: "br", want: false},
+		{header: "*", encoding: "br", want: true},
+		{header: "identity", encoding: "gzip", want: false},
+	}
+	for _, tt := range tests {
+		if got := acceptsEncoding(tt.header, tt.encoding); got != tt.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", tt.header, tt.encoding, got, tt.want)
+		}
+	}
+}
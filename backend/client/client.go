@@ -0,0 +1,238 @@
+// Package client is a Go client for the LCR API, for bots, load tests and tools.
+//
+//	c := client.New("https://lcr.example.com", client.WithToken(client.StaticToken(idToken)))
+//	created, err := c.CreateGame(ctx, &client.CreateGameRequest{Players: []client.NewPlayer{{Name: "Ada"}}})
+//
+// Errors answered by the server are returned as *Error and can be compared with errors.Is to the
+// Err variables of this package, e.g. errors.Is(err, client.ErrNotYourTurn).
+package client
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenSource returns the Firebase ID token sent with each request
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource always returning the same token
+type StaticToken string
+
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// TokenFunc turns a function into a TokenSource, e.g. to refresh tokens before they expire
+type TokenFunc func(ctx context.Context) (string, error)
+
+func (f TokenFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// Client calls the LCR API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	tokens     TokenSource
+	retries    int
+	backoff    time.Duration
+	userAgent  string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithToken sets where the ID token sent with each request comes from. Without it requests are
+// unauthenticated.
+func WithToken(tokens TokenSource) Option {
+	return func(c *Client) { c.tokens = tokens }
+}
+
+// WithHTTPClient sets the HTTP client requests are sent with
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries sets how many times a failed request is retried, and the delay before the first retry,
+// doubled for each following one. Defaults to 3 retries after 200ms.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New creates a client of the API served at baseURL, e.g. http://localhost:3000
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/v1",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retries:    3,
+		backoff:    200 * time.Millisecond,
+		userAgent:  "lcr-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// do sends a request with a JSON body, if in is not nil, and decodes the JSON response into out, if
+// it is not nil. Requests that are safe to repeat, or made so by an Idempotency-Key, are retried when
+// the server is unavailable.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	// The same key is sent with every attempt, so the server answers a retried POST with the response
+	// of the attempt it already handled instead of handling it again
+	var idempotencyKey string
+	if method == http.MethodPost || method == http.MethodPut {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	retries := 0
+	if isIdempotent(method) || idempotencyKey != "" {
+		retries = c.retries
+	}
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, query, body, idempotencyKey)
+		if err == nil {
+			err = decode(resp, out)
+		}
+		if err == nil || attempt >= retries || !retryable(err) {
+			return err
+		}
+
+		wait := delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// send sends one attempt of a request
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body []byte, idempotencyKey string) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if c.tokens != nil {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.httpClient.Do(req)
+}
+
+// decode reads a response into out, or into an *Error if the server answered with one
+func decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return newError(resp, data)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryable reports whether a failed attempt may succeed when sent again
+func retryable(err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		// Network errors, but not a canceled context
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch apiErr.Status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	// The attempt that timed out on the client is still being handled, its response can be replayed once done
+	return errors.Is(apiErr, ErrIdempotencyKeyInUse)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// newIdempotencyKey returns a random Idempotency-Key
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		// Without a key the request is sent as before, just not retried
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error is an error answered by the server, decoded from its error body
+type Error struct {
+	// Status is the HTTP status of the response
+	Status int `json:"code"`
+	// Code is the machine-readable error code, such as game_over
+	Code      string       `json:"error"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"requestId"`
+	TraceID   string       `json:"traceId"`
+	Fields    []FieldError `json:"fields"`
+	// RetryAfter is how long the server asked to wait before retrying, if it did
+	RetryAfter time.Duration `json:"-"`
+}
+
+// FieldError describes one invalid part of a request that failed validation
+type FieldError struct {
+	// In is where the field was sent: body, path, query or header
+	In      string `json:"in"`
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("lcr: %d %s: %s", e.Status, e.Code, e.Message)
+	for _, field := range e.Fields {
+		msg += fmt.Sprintf("; %s %s %s", field.In, field.Field, field.Message)
+	}
+	return msg
+}
+
+// Is reports whether target is an *Error with the same code, so that errors.Is(err, ErrGameOver) works
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// Errors answered by the server, to compare with errors.Is
+var (
+	ErrValidation           = &Error{Code: "validation_failed"}
+	ErrUnauthorized         = &Error{Code: "unauthorized"}
+	ErrForbidden            = &Error{Code: "forbidden"}
+	ErrNotFound             = &Error{Code: "not_found"}
+	ErrTooManyRequests      = &Error{Code: "too_many_requests"}
+	ErrUnavailable          = &Error{Code: "unavailable"}
+	ErrGameNotFound         = &Error{Code: "game_not_found"}
+	ErrPlayerNotFound       = &Error{Code: "player_not_found"}
+	ErrGameOver             = &Error{Code: "game_over"}
+	ErrGameNotStarted       = &Error{Code: "game_not_started"}
+	ErrGameStarted          = &Error{Code: "game_started"}
+	ErrNotEnoughPlayers     = &Error{Code: "not_enough_players"}
+	ErrPlayersNotReady      = &Error{Code: "players_not_ready"}
+	ErrLobbyFull            = &Error{Code: "lobby_full"}
+	ErrAlreadyInGame        = &Error{Code: "already_in_game"}
+	ErrNameTaken            = &Error{Code: "name_taken"}
+	ErrNotYourTurn          = &Error{Code: "not_your_turn"}
+	ErrNotInGame            = &Error{Code: "not_in_game"}
+	ErrHostOnly             = &Error{Code: "host_only"}
+	ErrPasswordRequired     = &Error{Code: "password_required"}
+	ErrWrongPassword        = &Error{Code: "wrong_password"}
+	ErrInviteOnly           = &Error{Code: "invite_only"}
+	ErrInviteInvalid        = &Error{Code: "invite_invalid"}
+	ErrInviteExpired        = &Error{Code: "invite_expired"}
+	ErrInviteUsed           = &Error{Code: "invite_used"}
+	ErrInvalidSettings      = &Error{Code: "invalid_settings"}
+	ErrIdempotencyKeyInUse  = &Error{Code: "idempotency_key_in_use"}
+	ErrIdempotencyKeyReused = &Error{Code: "idempotency_key_reused"}
+)
+
+// newError decodes the error body of a response. Responses not sent by the API, e.g. by a proxy, get
+// the body as message.
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{}
+	if err := json.Unmarshal(body, e); err != nil || e.Code == "" {
+		e = &Error{Code: codeForStatus(resp.StatusCode), Message: strings.TrimSpace(string(body))}
+	}
+	e.Status = resp.StatusCode
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	e.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+	return e
+}
+
+// codeForStatus returns the generic code the server uses for an HTTP status
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized.Code
+	case http.StatusForbidden:
+		return ErrForbidden.Code
+	case http.StatusNotFound:
+		return ErrNotFound.Code
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests.Code
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable.Code
+	}
+	if status >= http.StatusInternalServerError {
+		return "internal_error"
+	}
+	return "bad_request"
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"backend/model"
+)
+
+// DefaultRetryInterval is how long Subscribe waits after a failed fetch when no interval is given
+const DefaultRetryInterval = time.Second
+
+// longPollTimeout is how long each request of Subscribe waits for a change, below the 30s timeout of
+// the default HTTP client
+const longPollTimeout = 25 * time.Second
+
+// Event is a change of a game seen by Subscribe. Err is set when fetching the game failed; the
+// subscription carries on after errors the server may recover from.
+type Event struct {
+	Game *model.Game
+	Err  error
+}
+
+// Subscribe sends the game on the returned channel each time it changes, starting with its current
+// state. It long-polls the server for the next version of the game, waiting retryInterval after a
+// failed request. The channel is closed once the game is over or abandoned, when the game does not
+// exist, or when ctx is done.
+func (c *Client) Subscribe(ctx context.Context, gameID string, retryInterval time.Duration) <-chan Event {
+	if retryInterval <= 0 {
+		retryInterval = DefaultRetryInterval
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		var last *model.Game
+		for {
+			var game *model.Game
+			var err error
+			if last == nil {
+				game, err = c.GetGame(ctx, gameID)
+			} else {
+				game, err = c.WaitForGame(ctx, gameID, last.Version+1, longPollTimeout)
+			}
+
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				if !send(ctx, events, Event{Err: err}) || errors.Is(err, ErrGameNotFound) {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retryInterval):
+				}
+			case last == nil || game.Version != last.Version:
+				last = game
+				if !send(ctx, events, Event{Game: game}) || game.GameOver || game.Abandoned {
+					return
+				}
+			}
+		}
+	}()
+	return events
+}
+
+// send sends an event unless ctx is done first, reporting whether it was sent
+func send(ctx context.Context, events chan<- Event, event Event) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"backend/model"
+)
+
+// NewPlayer is a player of a game being created
+type NewPlayer struct {
+	Name string `json:"Name"`
+}
+
+// CreateGameRequest is the body of CreateGame. Settings left at their zero value get the server default.
+type CreateGameRequest struct {
+	Players  []NewPlayer `json:"Players"`
+	Password string      `json:"Password,omitempty"`
+	model.Settings
+}
+
+// CreateGameResponse is the game created by CreateGame
+type CreateGameResponse struct {
+	GameID    string        `json:"gameID"`
+	LobbyCode string        `json:"lobbyCode"`
+	Creator   *model.Player `json:"creator"`
+}
+
+// LobbyQuery filters and pages the lobbies listed by ListLobbies. Zero values are left out.
+type LobbyQuery struct {
+	Limit  int
+	Cursor string
+	// OldestFirst lists the oldest lobbies first instead of the newest
+	OldestFirst bool
+	// HasPassword, when set, only lists the lobbies with or without a password
+	HasPassword *bool
+	HasSeats    bool
+	RuleSet     string
+	MinPlayers  int
+	MaxPlayers  int
+}
+
+// LobbyPage is a page of lobbies. NextCursor is empty on the last page.
+type LobbyPage struct {
+	Lobbies    []*model.LobbySummary `json:"lobbies"`
+	NextCursor string                `json:"nextCursor,omitempty"`
+}
+
+// gameResponse is the body of the routes answering with a game
+type gameResponse struct {
+	Game *model.Game `json:"game"`
+}
+
+// gameIDResponse is the body of the routes answering with a game ID
+type gameIDResponse struct {
+	GameID string `json:"gameID"`
+}
+
+// CreateGame creates a lobby hosted by the calling user
+func (c *Client) CreateGame(ctx context.Context, req *CreateGameRequest) (*CreateGameResponse, error) {
+	resp := &CreateGameResponse{}
+	if err := c.do(ctx, http.MethodPost, "/games", nil, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// JoinGame joins the lobby with the given code or invite token as a player named name, and returns
+// the ID of its game. password is only needed for password-protected lobbies joined without an invite.
+func (c *Client) JoinGame(ctx context.Context, lobbyCode, name, password string) (string, error) {
+	body := map[string]string{"Name": name}
+	if password != "" {
+		body["Password"] = password
+	}
+	resp := &gameIDResponse{}
+	if err := c.do(ctx, http.MethodPost, "/lobbies/"+url.PathEscape(lobbyCode)+"/players", nil, body, resp); err != nil {
+		return "", err
+	}
+	return resp.GameID, nil
+}
+
+// GameIDByLobbyCode returns the ID of the game using a lobby code
+func (c *Client) GameIDByLobbyCode(ctx context.Context, lobbyCode string) (string, error) {
+	resp := &gameIDResponse{}
+	if err := c.do(ctx, http.MethodGet, "/lobbies/"+url.PathEscape(lobbyCode), nil, nil, resp); err != nil {
+		return "", err
+	}
+	return resp.GameID, nil
+}
+
+// SetReady marks a player of the lobby ready to start
+func (c *Client) SetReady(ctx context.Context, lobbyCode, playerName string) (*model.Game, error) {
+	game := &model.Game{}
+	path := "/lobbies/" + url.PathEscape(lobbyCode) + "/players/" + url.PathEscape(playerName) + "/ready"
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, game); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// Leave removes a player from the lobby. The player's own user and the host may remove them.
+func (c *Client) Leave(ctx context.Context, lobbyCode, playerName string) (*model.Game, error) {
+	game := &model.Game{}
+	path := "/lobbies/" + url.PathEscape(lobbyCode) + "/players/" + url.PathEscape(playerName)
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil, game); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// AddBots adds bots to the free seats of the lobby. Only the host can add them.
+func (c *Client) AddBots(ctx context.Context, lobbyCode string) (*model.Game, error) {
+	game := &model.Game{}
+	if err := c.do(ctx, http.MethodPost, "/lobbies/"+url.PathEscape(lobbyCode)+"/bots", nil, nil, game); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// SetBotsReady marks every bot of the lobby ready. Only the host can ready them.
+func (c *Client) SetBotsReady(ctx context.Context, lobbyCode string) (*model.Game, error) {
+	game := &model.Game{}
+	if err := c.do(ctx, http.MethodPost, "/lobbies/"+url.PathEscape(lobbyCode)+"/bots/ready", nil, nil, game); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+// StartGame starts the game of the lobby. Only the host can start it, once every player is ready.
+func (c *Client) StartGame(ctx context.Context, lobbyCode string) (*model.Game, error) {
+	resp := &gameResponse{}
+	if err := c.do(ctx, http.MethodPost, "/lobbies/"+url.PathEscape(lobbyCode)+"/start", nil, nil, resp); err != nil {
+		return nil, err
+	}
+	return resp.Game, nil
+}
+
+// TakeTurn rolls for the player whose turn it is, which must be the calling user, or a bot when the user hosts the game
+func (c *Client) TakeTurn(ctx context.Context, gameID string) (*model.Game, error) {
+	resp := &gameResponse{}
+	if err := c.do(ctx, http.MethodPost, "/games/"+url.PathEscape(gameID)+"/turns", nil, nil, resp); err != nil {
+		return nil, err
+	}
+	return resp.Game, nil
+}
+
+// GetGame returns the current state of a game
+func (c *Client) GetGame(ctx context.Context, gameID string) (*model.Game, error) {
+	resp := &gameResponse{}
+	if err := c.do(ctx, http.MethodGet, "/games/"+url.PathEscape(gameID), nil, nil, resp); err != nil {
+		return nil, err
+	}
+	return resp.Game, nil
+}
+
+// WaitForGame returns the game once its version is at least version, or as it is after timeout, which
+// must be shorter than the timeout of the HTTP client
+func (c *Client) WaitForGame(ctx context.Context, gameID string, version int64, timeout time.Duration) (*model.Game, error) {
+	query := url.Values{}
+	query.Set("waitForVersion", strconv.FormatInt(version, 10))
+	query.Set("timeout", timeout.String())
+	resp := &gameResponse{}
+	if err := c.do(ctx, http.MethodGet, "/games/"+url.PathEscape(gameID), query, nil, resp); err != nil {
+		return nil, err
+	}
+	return resp.Game, nil
+}
+
+// ListLobbies returns a page of the public open lobbies
+func (c *Client) ListLobbies(ctx context.Context, q LobbyQuery) (*LobbyPage, error) {
+	query := url.Values{}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Cursor != "" {
+		query.Set("cursor", q.Cursor)
+	}
+	if q.OldestFirst {
+		query.Set("sort", "oldest")
+	}
+	if q.HasPassword != nil {
+		query.Set("hasPassword", strconv.FormatBool(*q.HasPassword))
+	}
+	if q.HasSeats {
+		query.Set("hasSeats", "true")
+	}
+	if q.RuleSet != "" {
+		query.Set("ruleSet", q.RuleSet)
+	}
+	if q.MinPlayers > 0 {
+		query.Set("minPlayers", strconv.Itoa(q.MinPlayers))
+	}
+	if q.MaxPlayers > 0 {
+		query.Set("maxPlayers", strconv.Itoa(q.MaxPlayers))
+	}
+
+	page := &LobbyPage{}
+	if err := c.do(ctx, http.MethodGet, "/lobbies", query, nil, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"backend/client"
+	"backend/model"
+)
+
+// lobbies lists the open public lobbies
+func lobbies(ctx context.Context, args []string) error {
+	fs, sf := newFlagSet("lobbies")
+	q := client.LobbyQuery{}
+	fs.IntVar(&q.Limit, "limit", 20, "number of lobbies to list, at most 100")
+	fs.BoolVar(&q.HasSeats, "has-seats", false, "only list lobbies with free seats")
+	noPassword := fs.Bool("no-password", false, "only list lobbies without a password")
+	fs.StringVar(&q.RuleSet, "rules", "", "only list lobbies using this rule set, classic or wild")
+	fs.Parse(args)
+	if *noPassword {
+		q.HasPassword = new(bool)
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	page, err := c.ListLobbies(ctx, q)
+	if err != nil {
+		return err
+	}
+	if len(page.Lobbies) == 0 {
+		fmt.Println("No open lobbies")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CODE\tHOST\tPLAYERS\tRULES\tPASSWORD")
+	for _, lobby := range page.Lobbies {
+		password := ""
+		if lobby.HasPassword {
+			password = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\t%s\n", lobby.LobbyCode, lobby.Creator, lobby.Players, lobby.MaxPlayers, lobby.RuleSet, password)
+	}
+	return w.Flush()
+}
+
+// create creates a lobby hosted by the user and plays it
+func create(ctx context.Context, args []string) error {
+	fs, sf := newFlagSet("create")
+	name := fs.String("name", "", "your player name")
+	password := fs.String("password", "", "lobby password")
+	settings := model.Settings{}
+	fs.IntVar(&settings.MaxPlayers, "max-players", 0, "seats in the lobby, bots included (default 8)")
+	fs.StringVar(&settings.RuleSet, "rules", "", "rule set, classic or wild (default classic)")
+	fs.IntVar(&settings.StartingChips, "chips", 0, "chips each player starts with (default 3)")
+	fs.StringVar(&settings.Visibility, "visibility", "", "public, unlisted or private (default public)")
+	fs.Parse(args)
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	created, err := c.CreateGame(ctx, &client.CreateGameRequest{
+		Players:  []client.NewPlayer{{Name: *name}},
+		Password: *password,
+		Settings: settings,
+	})
+	if err != nil {
+		return err
+	}
+
+	return newTable(c, created.GameID, *name).play(ctx, os.Stdin)
+}
+
+// join joins a lobby by code or invite token and plays it
+func join(ctx context.Context, args []string) error {
+	fs, sf := newFlagSet("join")
+	name := fs.String("name", "", "your player name")
+	password := fs.String("password", "", "lobby password, if it has one and you have no invite")
+	fs.Parse(args)
+	if *name == "" || fs.NArg() != 1 {
+		return errors.New("usage: lcrctl join -name <name> <lobby code or invite token>")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	gameID, err := c.JoinGame(ctx, fs.Arg(0), *name, *password)
+	if err != nil {
+		return err
+	}
+
+	return newTable(c, gameID, *name).play(ctx, os.Stdin)
+}
+
+// watch shows a game live without playing it
+func watch(ctx context.Context, args []string) error {
+	fs, sf := newFlagSet("watch")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: lcrctl watch <game ID>")
+	}
+
+	c, err := sf.newClient()
+	if err != nil {
+		return err
+	}
+	return newTable(c, fs.Arg(0), "").play(ctx, nil)
+}
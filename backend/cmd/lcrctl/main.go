@@ -0,0 +1,77 @@
+// Command lcrctl plays LCR from the terminal, through the /v1 API.
+//
+//	lcrctl login -token <Firebase ID token> [-server https://lcr.example.com]
+//	lcrctl lobbies
+//	lcrctl create -name Ada [-max-players 6] [-rules wild] [-chips 3] [-password secret]
+//	lcrctl join -name Bob [-password secret] <lobby code or invite token>
+//	lcrctl watch <game ID>
+//
+// create and join show the game live and read commands from stdin: r to get ready, b to fill the
+// lobby with bots and s to start (host only), Enter to roll on your turn and q to quit.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "login":
+		err = login(args)
+	case "lobbies":
+		err = lobbies(ctx, args)
+	case "create":
+		err = create(ctx, args)
+	case "join":
+		err = join(ctx, args)
+	case "watch":
+		err = watch(ctx, args)
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "lcrctl: unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lcrctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage: lcrctl <command> [flags]
+
+Commands:
+  login    save the server URL and the Firebase ID token to use
+  lobbies  list the open public lobbies
+  create   create a lobby and play it
+  join     join a lobby by code or invite token and play it
+  watch    show a game live without playing
+
+Run lcrctl <command> -h for the flags of a command.
+`)
+}
+
+// newFlagSet creates the flag set of a command, with the flags every command accepts
+func newFlagSet(name string) (*flag.FlagSet, *sessionFlags) {
+	fs := flag.NewFlagSet("lcrctl "+name, flag.ExitOnError)
+	sf := &sessionFlags{}
+	fs.StringVar(&sf.server, "server", "", "server URL (default from login, LCR_SERVER or "+defaultServer+")")
+	fs.StringVar(&sf.token, "token", "", "Firebase ID token (default from login or LCR_TOKEN)")
+	return fs, sf
+}
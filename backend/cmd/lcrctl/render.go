@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"backend/model"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// render draws the game and the status line, replacing the previous drawing on a terminal
+func (t *table) render() {
+	var b strings.Builder
+	if isTerminal(t.out) {
+		b.WriteString(clearScreen)
+	} else {
+		b.WriteString("\n")
+	}
+
+	game := t.game
+	if game == nil {
+		b.WriteString("Loading game...\n")
+	} else {
+		writeGame(&b, game, t.name)
+		b.WriteString("\n")
+		b.WriteString(t.prompt())
+	}
+	if t.status != "" {
+		fmt.Fprintf(&b, "\n> %s\n", t.status)
+	}
+	fmt.Fprint(t.out, b.String())
+}
+
+// writeGame writes the header, the players and the last roll of a game
+func writeGame(b *strings.Builder, game *model.Game, name string) {
+	settings := game.Settings.WithDefaults()
+	fmt.Fprintf(b, "LCR  lobby %s  game %s  %s rules  %s\n\n", game.LobbyCode, game.GameID, settings.RuleSet, strings.ReplaceAll(game.Status(), "_", " "))
+
+	current := currentPlayer(game)
+	w := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+	for _, player := range game.Players {
+		marker := "  "
+		if game.Started && !game.GameOver && player == current {
+			marker = "> "
+		}
+		label := player.Name
+		if player.Name == name {
+			label += " (you)"
+		}
+		if player.IsBot() {
+			label += " [bot]"
+		}
+		var state string
+		switch {
+		case !game.Started && player.LobbyStatus:
+			state = "ready"
+		case !game.Started:
+			state = "not ready"
+		default:
+			state = strings.Repeat("o", player.Chips)
+		}
+		fmt.Fprintf(w, "%s%s\t%d\t%s\n", marker, label, player.Chips, state)
+	}
+	w.Flush()
+
+	fmt.Fprintf(b, "\nPot: %d\n", game.Pot)
+	if n := len(game.History); n > 0 {
+		last := game.History[n-1]
+		fmt.Fprintf(b, "Last roll: %s rolled %s\n", last.Player, faces(last.Rolls, settings.RuleSet))
+	}
+}
+
+// prompt tells the user what they can do next
+func (t *table) prompt() string {
+	game := t.game
+	switch {
+	case game.GameOver && game.Winner != nil:
+		return fmt.Sprintf("%s wins!\n", game.Winner.Name)
+	case game.GameOver:
+		return "The game is over.\n"
+	case t.name == "":
+		return "Watching. Press Ctrl+C to stop.\n"
+	case !game.Started && t.isHost():
+		return "[r] ready  [b] fill with bots  [s] start  [q] quit\n"
+	case !game.Started:
+		return "[r] ready  [q] quit. Waiting for the host to start.\n"
+	}
+	current := currentPlayer(game)
+	if current == nil {
+		return "\n"
+	}
+	if current.Name == t.name {
+		return "Your turn! Press Enter to roll.\n"
+	}
+	return fmt.Sprintf("Waiting for %s to roll...\n", current.Name)
+}
+
+// faces spells out dice rolls: L, C and R pass a chip, W is a wild and . keeps it
+func faces(rolls []int, ruleSet string) string {
+	if len(rolls) == 0 {
+		return "nothing"
+	}
+	out := make([]string, len(rolls))
+	for i, roll := range rolls {
+		switch {
+		case roll == 4:
+			out[i] = "L"
+		case roll == 5:
+			out[i] = "C"
+		case roll == 6:
+			out[i] = "R"
+		case roll == model.WildFace && ruleSet == model.RuleSetWild:
+			out[i] = "W"
+		default:
+			out[i] = "."
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// isTerminal reports whether w is a terminal, so the screen can be redrawn in place
+func isTerminal(w interface{}) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
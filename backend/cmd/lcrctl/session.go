@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"backend/client"
+)
+
+const defaultServer = "http://localhost:3000"
+
+// session is what login saves for the following commands
+type session struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+// sessionFlags override the saved session for one command
+type sessionFlags struct {
+	server string
+	token  string
+}
+
+// sessionPath returns where the session is saved, in the user config directory
+func sessionPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lcrctl", "session.json"), nil
+}
+
+// loadSession reads the saved session, which is empty if login was never run
+func loadSession() (*session, error) {
+	s := &session{}
+	path, err := sessionPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// save writes the session, readable only by the user as it holds the token
+func (s *session) save() (string, error) {
+	path, err := sessionPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o600)
+}
+
+// newClient creates an API client from the flags, the environment and the saved session, in that order
+func (sf *sessionFlags) newClient() (*client.Client, error) {
+	s, err := loadSession()
+	if err != nil {
+		return nil, err
+	}
+	server := firstNonEmpty(sf.server, os.Getenv("LCR_SERVER"), s.Server, defaultServer)
+	token := firstNonEmpty(sf.token, os.Getenv("LCR_TOKEN"), s.Token)
+	if token == "" {
+		return nil, errors.New("no token, run lcrctl login -token <Firebase ID token> first")
+	}
+	return client.New(server, client.WithToken(client.StaticToken(token)), client.WithUserAgent("lcrctl")), nil
+}
+
+// login saves the server and token given as flags
+func login(args []string) error {
+	fs, sf := newFlagSet("login")
+	fs.Parse(args)
+	if sf.token == "" {
+		return errors.New("-token is required")
+	}
+
+	s, err := loadSession()
+	if err != nil {
+		return err
+	}
+	s.Token = sf.token
+	if sf.server != "" {
+		s.Server = sf.server
+	}
+	path, err := s.save()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Logged in to %s, session saved to %s\n", firstNonEmpty(s.Server, defaultServer), path)
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"backend/client"
+	"backend/model"
+)
+
+const (
+	// retryInterval is how long to wait before following the game again after a failed request
+	retryInterval = time.Second
+	// botDelay is how long the host waits before rolling for a bot, so turns can be followed
+	botDelay = 800 * time.Millisecond
+)
+
+// table follows one game, rendering it on every change and sending the commands of the player
+type table struct {
+	c      *client.Client
+	gameID string
+	// name is the player of the user, empty when only watching
+	name   string
+	game   *model.Game
+	status string
+	out    io.Writer
+	// botTurn fires when the host should roll for the bot whose turn it is
+	botTurn <-chan time.Time
+}
+
+func newTable(c *client.Client, gameID, name string) *table {
+	return &table{c: c, gameID: gameID, name: name, out: os.Stdout}
+}
+
+// play renders the game until it ends or ctx is done, reading commands from in if it is not nil
+func (t *table) play(ctx context.Context, in io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := t.c.Subscribe(ctx, t.gameID, retryInterval)
+	var lines <-chan string
+	if in != nil {
+		lines = readLines(in)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if event.Err != nil {
+				t.status = event.Err.Error()
+				t.render()
+				continue
+			}
+			t.update(event.Game)
+		case <-t.botTurn:
+			t.botTurn = nil
+			t.roll(ctx)
+		case line, ok := <-lines:
+			if !ok {
+				lines = nil
+				continue
+			}
+			if quit := t.command(ctx, strings.TrimSpace(line)); quit {
+				return nil
+			}
+		}
+	}
+}
+
+// update shows a newer state of the game, and schedules the roll of a bot if the user hosts it
+func (t *table) update(game *model.Game) {
+	if game == nil || (t.game != nil && game.Version <= t.game.Version) {
+		return
+	}
+	t.game = game
+	t.render()
+
+	current := currentPlayer(game)
+	if t.isHost() && game.Started && !game.GameOver && current != nil && current.IsBot() {
+		t.botTurn = time.After(botDelay)
+	}
+}
+
+// command runs a command typed by the user, reporting whether they asked to quit
+func (t *table) command(ctx context.Context, cmd string) bool {
+	game := t.game
+	if game == nil {
+		return false
+	}
+
+	var err error
+	switch strings.ToLower(cmd) {
+	case "q", "quit":
+		return true
+	case "", "roll":
+		current := currentPlayer(game)
+		switch {
+		case !game.Started:
+			t.status = "The game has not started yet"
+		case current == nil || current.Name != t.name:
+			t.status = "It is not your turn"
+		default:
+			t.roll(ctx)
+			return false
+		}
+	case "r", "ready":
+		var updated *model.Game
+		if updated, err = t.c.SetReady(ctx, game.LobbyCode, t.name); err == nil {
+			t.status = "You are ready"
+			t.update(updated)
+		}
+	case "b", "bots":
+		if _, err = t.c.AddBots(ctx, game.LobbyCode); err == nil {
+			var updated *model.Game
+			if updated, err = t.c.SetBotsReady(ctx, game.LobbyCode); err == nil {
+				t.status = "Bots added"
+				t.update(updated)
+			}
+		}
+	case "s", "start":
+		var updated *model.Game
+		if updated, err = t.c.StartGame(ctx, game.LobbyCode); err == nil {
+			t.status = "The game has started"
+			t.update(updated)
+		}
+	default:
+		t.status = fmt.Sprintf("Unknown command %q", cmd)
+	}
+	if err != nil {
+		t.status = err.Error()
+	}
+	t.render()
+	return false
+}
+
+// roll takes the turn of the current player
+func (t *table) roll(ctx context.Context) {
+	game, err := t.c.TakeTurn(ctx, t.gameID)
+	if err != nil {
+		t.status = err.Error()
+		t.render()
+		return
+	}
+	t.status = ""
+	t.update(game)
+}
+
+func (t *table) isHost() bool {
+	return t.name != "" && t.game != nil && t.game.Creator != nil && t.game.Creator.Name == t.name
+}
+
+// currentPlayer returns the player whose turn it is
+func currentPlayer(game *model.Game) *model.Player {
+	if game.Turn < 0 || game.Turn >= len(game.Players) {
+		return nil
+	}
+	return game.Players[game.Turn]
+}
+
+// readLines sends the lines read from r, and closes the channel at the end of r
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
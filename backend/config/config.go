@@ -0,0 +1,554 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of the server. It is built from defaults, then an optional JSON file,
+// then environment variables, then command-line flags, each overriding the previous one.
+type Config struct {
+	Server      Server      `json:"server"`
+	Postgres    Postgres    `json:"postgres"`
+	Firebase    Firebase    `json:"firebase"`
+	Admin       Admin       `json:"admin"`
+	Janitor     Janitor     `json:"janitor"`
+	LobbyCode   LobbyCode   `json:"lobbyCode"`
+	Invites     Invites     `json:"invites"`
+	Idempotency Idempotency `json:"idempotency"`
+	RateLimits  RateLimits  `json:"rateLimits"`
+	Logging     Logging     `json:"logging"`
+	Tracing     Tracing     `json:"tracing"`
+}
+
+// Server configures the HTTP listener
+type Server struct {
+	Addr         string   `json:"addr"`
+	AllowOrigins []string `json:"allowOrigins"`
+	// ShutdownTimeout is how long in-flight requests may take to finish once a shutdown starts
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+	// ProxyHeader is the header holding the client IP set by the proxy in front of the server, such
+	// as X-Forwarded-For. Empty uses the address of the connection.
+	ProxyHeader string `json:"proxyHeader"`
+}
+
+// Postgres configures the PostgreSQL connection
+type Postgres struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Database string `json:"database"`
+	SSLMode  string `json:"sslMode"`
+}
+
+// DSN returns the connection string for lib/pq
+func (p Postgres) DSN() string {
+	dsn := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(p.User, p.Password),
+		Host:   fmt.Sprintf("%s:%d", p.Host, p.Port),
+		Path:   "/" + p.Database,
+	}
+	if p.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {p.SSLMode}}.Encode()
+	}
+	return dsn.String()
+}
+
+// Firebase credential sources
+const (
+	// CredentialsPostgres reads the service account from the credentials column of the firebase table in Postgres
+	CredentialsPostgres = "postgres"
+	// CredentialsFile reads the service account from the JSON file at CredentialsFile
+	CredentialsFile = "file"
+	// CredentialsEnv reads the service account from the base64 encoded JSON in CredentialsBase64
+	CredentialsEnv = "env"
+	// CredentialsDefault uses Google Application Default Credentials
+	CredentialsDefault = "default"
+)
+
+// Firebase configures the Firebase apps
+type Firebase struct {
+	DatabaseURL string `json:"databaseURL"`
+	// CredentialsSource picks where the service account is read from, one of the Credentials* sources
+	CredentialsSource string `json:"credentialsSource"`
+	CredentialsFile   string `json:"credentialsFile"`
+	CredentialsBase64 string `json:"credentialsBase64"`
+}
+
+// UsesPostgres reports whether the server needs a PostgreSQL connection
+func (c *Config) UsesPostgres() bool {
+	return c.Firebase.CredentialsSource == CredentialsPostgres
+}
+
+// Admin configures who may use the admin API besides users with the admin claim
+type Admin struct {
+	UIDs []string `json:"uids"`
+}
+
+// Janitor configures the background cleanup of lobbies and games
+type Janitor struct {
+	Interval         Duration `json:"interval"`
+	LobbyIdleTimeout Duration `json:"lobbyIdleTimeout"`
+	GameIdleTimeout  Duration `json:"gameIdleTimeout"`
+	Retention        Duration `json:"retention"`
+}
+
+// LobbyCode configures the shape of lobby codes. A zero length picks the default for the style.
+type LobbyCode struct {
+	Style  string `json:"style"`
+	Length int    `json:"length"`
+}
+
+// Idempotency configures how long the responses of requests with an Idempotency-Key are kept
+type Idempotency struct {
+	TTL Duration `json:"ttl"`
+}
+
+// RateLimits configures the rate limit of each class of routes, applied per user, or per client IP on
+// the routes that need no token
+type RateLimits struct {
+	Enabled bool      `json:"enabled"`
+	Read    RateLimit `json:"read"`
+	Write   RateLimit `json:"write"`
+	Create  RateLimit `json:"create"`
+	Public  RateLimit `json:"public"`
+}
+
+// RateLimit lets Requests requests through per Per on average, in bursts of up to Burst requests
+type RateLimit struct {
+	Requests int      `json:"requests"`
+	Per      Duration `json:"per"`
+	Burst    int      `json:"burst"`
+}
+
+// Invites configures invite signing
+type Invites struct {
+	// Secret signs invite tokens. When empty a random secret is used and invites do not survive a restart.
+	Secret  string `json:"secret"`
+	BaseURL string `json:"baseURL"`
+}
+
+// Logging configures the server logs
+type Logging struct {
+	// Level is the minimum level logged: debug, info, warn or error
+	Level string `json:"level"`
+	// Format is json for one JSON object per line, or text for logfmt-style lines that are easier to read locally
+	Format string `json:"format"`
+	// HashKey keys the hashes user IDs and client IPs are logged as. When empty a random key is used and the
+	// hashes of a user change when the server restarts.
+	HashKey string `json:"hashKey"`
+}
+
+// Tracing configures OpenTelemetry tracing
+type Tracing struct {
+	// Exporter is none, stdout, file or otlp
+	Exporter string `json:"exporter"`
+	// Endpoint is the OTLP/HTTP collector address. When empty the standard OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint string `json:"endpoint"`
+	Insecure bool   `json:"insecure"`
+	// File is where the file exporter appends spans
+	File string `json:"file"`
+	// SampleRatio is the fraction of new traces recorded, between 0 and 1
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// Duration is a time.Duration written as a Go duration string such as "30m" in config files
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30m\"")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:            "0.0.0.0:3000",
+			AllowOrigins:    []string{"http://localhost:5173"},
+			ShutdownTimeout: Duration{15 * time.Second},
+		},
+		Postgres: Postgres{
+			Port:     5432,
+			User:     "postgres",
+			Database: "railway",
+		},
+		Firebase: Firebase{
+			CredentialsSource: CredentialsPostgres,
+		},
+		Janitor: Janitor{
+			Interval:         Duration{5 * time.Minute},
+			LobbyIdleTimeout: Duration{30 * time.Minute},
+			GameIdleTimeout:  Duration{24 * time.Hour},
+			Retention:        Duration{7 * 24 * time.Hour},
+		},
+		LobbyCode: LobbyCode{
+			Style: "chars",
+		},
+		Invites: Invites{
+			BaseURL: "https://lcr.up.railway.app/join/",
+		},
+		Idempotency: Idempotency{
+			TTL: Duration{24 * time.Hour},
+		},
+		RateLimits: RateLimits{
+			Enabled: true,
+			Read:    RateLimit{Requests: 300, Per: Duration{time.Minute}, Burst: 60},
+			Write:   RateLimit{Requests: 60, Per: Duration{time.Minute}, Burst: 20},
+			Create:  RateLimit{Requests: 20, Per: Duration{time.Hour}, Burst: 5},
+			Public:  RateLimit{Requests: 30, Per: Duration{time.Minute}, Burst: 10},
+		},
+		Logging: Logging{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			File:        "traces.json",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load builds the configuration from the JSON file given by -config or LCR_CONFIG, the environment and
+// the command-line flags in args, then validates it
+func Load(args []string) (*Config, error) {
+	return LoadFlags(flag.NewFlagSet("backend", flag.ContinueOnError), args)
+}
+
+// LoadFlags is Load parsing args with fs, so commands can add their own flags to fs before
+func LoadFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+
+	configPath := fs.String("config", os.Getenv("LCR_CONFIG"), "path to a JSON config file")
+	addr := fs.String("addr", "", "address to listen on, e.g. 0.0.0.0:3000")
+	allowOrigins := fs.String("allow-origins", "", "comma-separated CORS origins")
+	postgresHost := fs.String("postgres-host", "", "PostgreSQL host")
+	postgresPort := fs.Int("postgres-port", 0, "PostgreSQL port")
+	postgresDatabase := fs.String("postgres-db", "", "PostgreSQL database")
+	firebaseURL := fs.String("firebase-url", "", "Firebase Realtime Database URL")
+	credentialsSource := fs.String("firebase-credentials", "", "where to read the Firebase service account from: postgres, file, env or default")
+	logLevel := fs.String("log-level", "", "minimum log level: debug, info, warn or error")
+	credentialsFile := fs.String("firebase-credentials-file", "", "path to the Firebase service account JSON file")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := loadFile(cfg, *configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	// Flags only override what was explicitly passed
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Server.Addr = *addr
+		case "allow-origins":
+			cfg.Server.AllowOrigins = splitList(*allowOrigins)
+		case "postgres-host":
+			cfg.Postgres.Host = *postgresHost
+		case "postgres-port":
+			cfg.Postgres.Port = *postgresPort
+		case "postgres-db":
+			cfg.Postgres.Database = *postgresDatabase
+		case "firebase-url":
+			cfg.Firebase.DatabaseURL = *firebaseURL
+		case "firebase-credentials":
+			cfg.Firebase.CredentialsSource = *credentialsSource
+		case "log-level":
+			cfg.Logging.Level = *logLevel
+		case "firebase-credentials-file":
+			cfg.Firebase.CredentialsFile = *credentialsFile
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+func loadEnv(cfg *Config) error {
+	var errs []error
+
+	// PORT is set by the hosting platform, LCR_ADDR wins over it
+	if port := os.Getenv("PORT"); port != "" {
+		cfg.Server.Addr = "0.0.0.0:" + port
+	}
+	envString("LCR_ADDR", &cfg.Server.Addr)
+	envList("CORS_ALLOW_ORIGINS", &cfg.Server.AllowOrigins)
+	errs = append(errs, envDuration("SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout))
+	envString("PROXY_HEADER", &cfg.Server.ProxyHeader)
+
+	envString("POSTGRES_HOST", &cfg.Postgres.Host)
+	errs = append(errs, envInt("POSTGRES_PORT", &cfg.Postgres.Port))
+	envString("POSTGRES_USER", &cfg.Postgres.User)
+	envString("POSTGRES_PASSWORD", &cfg.Postgres.Password)
+	envString("POSTGRES_DB", &cfg.Postgres.Database)
+	envString("POSTGRES_SSLMODE", &cfg.Postgres.SSLMode)
+
+	envString("FIREBASE_DATABASE_URL", &cfg.Firebase.DatabaseURL)
+	envString("FIREBASE_CREDENTIALS_SOURCE", &cfg.Firebase.CredentialsSource)
+	envString("FIREBASE_CREDENTIALS_FILE", &cfg.Firebase.CredentialsFile)
+	envString("FIREBASE_CREDENTIALS_BASE64", &cfg.Firebase.CredentialsBase64)
+
+	envList("ADMIN_UIDS", &cfg.Admin.UIDs)
+
+	errs = append(errs,
+		envDuration("JANITOR_INTERVAL", &cfg.Janitor.Interval),
+		envDuration("JANITOR_LOBBY_IDLE_TIMEOUT", &cfg.Janitor.LobbyIdleTimeout),
+		envDuration("JANITOR_GAME_IDLE_TIMEOUT", &cfg.Janitor.GameIdleTimeout),
+		envDuration("JANITOR_RETENTION", &cfg.Janitor.Retention),
+	)
+
+	envString("LOBBY_CODE_STYLE", &cfg.LobbyCode.Style)
+	errs = append(errs, envInt("LOBBY_CODE_LENGTH", &cfg.LobbyCode.Length))
+
+	envString("INVITE_SECRET", &cfg.Invites.Secret)
+	envString("INVITE_BASE_URL", &cfg.Invites.BaseURL)
+
+	errs = append(errs, envDuration("IDEMPOTENCY_TTL", &cfg.Idempotency.TTL))
+
+	errs = append(errs,
+		envBool("RATE_LIMITS_ENABLED", &cfg.RateLimits.Enabled),
+		envRateLimit("RATE_LIMIT_READ", &cfg.RateLimits.Read),
+		envRateLimit("RATE_LIMIT_WRITE", &cfg.RateLimits.Write),
+		envRateLimit("RATE_LIMIT_CREATE", &cfg.RateLimits.Create),
+		envRateLimit("RATE_LIMIT_PUBLIC", &cfg.RateLimits.Public),
+	)
+
+	envString("LOG_LEVEL", &cfg.Logging.Level)
+	envString("LOG_FORMAT", &cfg.Logging.Format)
+	envString("LOG_HASH_KEY", &cfg.Logging.HashKey)
+
+	envString("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	envString("TRACING_ENDPOINT", &cfg.Tracing.Endpoint)
+	errs = append(errs, envBool("TRACING_INSECURE", &cfg.Tracing.Insecure))
+	envString("TRACING_FILE", &cfg.Tracing.File)
+	errs = append(errs, envFloat("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio))
+
+	return errors.Join(errs...)
+}
+
+func envString(key string, target *string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*target = value
+	}
+}
+
+func envList(key string, target *[]string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*target = splitList(value)
+	}
+}
+
+func envInt(key string, target *int) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("config: %s=%q is not a number", key, value)
+	}
+	*target = n
+	return nil
+}
+
+func envBool(key string, target *bool) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("config: %s=%q is not true or false", key, value)
+	}
+	*target = b
+	return nil
+}
+
+func envFloat(key string, target *float64) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("config: %s=%q is not a number", key, value)
+	}
+	*target = f
+	return nil
+}
+
+func envDuration(key string, target *Duration) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("config: %s=%q is not a duration such as 30m", key, value)
+	}
+	target.Duration = d
+	return nil
+}
+
+// envRateLimit parses a rate limit such as 60/1m, or 60/1m,20 to set the burst, which is the number
+// of requests otherwise
+func envRateLimit(key string, target *RateLimit) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	invalid := fmt.Errorf("config: %s=%q is not a rate limit such as 60/1m or 60/1m,20", key, value)
+	limit, burst, hasBurst := strings.Cut(value, ",")
+	requests, per, ok := strings.Cut(limit, "/")
+	if !ok {
+		return invalid
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil {
+		return invalid
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil {
+		return invalid
+	}
+	b := n
+	if hasBurst {
+		if b, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil {
+			return invalid
+		}
+	}
+	*target = RateLimit{Requests: n, Per: Duration{d}, Burst: b}
+	return nil
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Validate reports every setting that is missing or out of range
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("config: "+format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr is required (LCR_ADDR, PORT or -addr)")
+	check(len(c.Server.AllowOrigins) > 0, "server.allowOrigins needs at least one origin (CORS_ALLOW_ORIGINS or -allow-origins)")
+	check(c.Server.ShutdownTimeout.Duration > 0, "server.shutdownTimeout must be positive")
+
+	// Postgres only holds the Firebase credentials, so it is only needed when they are read from there
+	if c.UsesPostgres() {
+		check(c.Postgres.Host != "", "postgres.host is required (POSTGRES_HOST or -postgres-host)")
+		check(c.Postgres.Port > 0 && c.Postgres.Port < 65536, "postgres.port %d is not a valid port", c.Postgres.Port)
+		check(c.Postgres.User != "", "postgres.user is required (POSTGRES_USER)")
+		check(c.Postgres.Password != "", "postgres.password is required (POSTGRES_PASSWORD)")
+		check(c.Postgres.Database != "", "postgres.database is required (POSTGRES_DB or -postgres-db)")
+	}
+
+	databaseURL, err := url.Parse(c.Firebase.DatabaseURL)
+	check(c.Firebase.DatabaseURL != "", "firebase.databaseURL is required (FIREBASE_DATABASE_URL or -firebase-url)")
+	check(c.Firebase.DatabaseURL == "" || (err == nil && databaseURL.Scheme == "https" && databaseURL.Host != ""),
+		"firebase.databaseURL %q must be an https URL", c.Firebase.DatabaseURL)
+	switch c.Firebase.CredentialsSource {
+	case CredentialsPostgres, CredentialsDefault:
+	case CredentialsFile:
+		check(c.Firebase.CredentialsFile != "", "firebase.credentialsFile is required when reading credentials from a file (FIREBASE_CREDENTIALS_FILE or -firebase-credentials-file)")
+	case CredentialsEnv:
+		check(c.Firebase.CredentialsBase64 != "", "firebase.credentialsBase64 is required when reading credentials from the environment (FIREBASE_CREDENTIALS_BASE64)")
+	default:
+		check(false, "firebase.credentialsSource %q must be %s, %s, %s or %s", c.Firebase.CredentialsSource,
+			CredentialsPostgres, CredentialsFile, CredentialsEnv, CredentialsDefault)
+	}
+
+	check(c.Janitor.Interval.Duration > 0, "janitor.interval must be positive")
+	check(c.Janitor.LobbyIdleTimeout.Duration > 0, "janitor.lobbyIdleTimeout must be positive")
+	check(c.Janitor.GameIdleTimeout.Duration > 0, "janitor.gameIdleTimeout must be positive")
+	check(c.Janitor.Retention.Duration > 0, "janitor.retention must be positive")
+
+	check(c.Invites.BaseURL != "", "invites.baseURL is required (INVITE_BASE_URL)")
+	check(c.Idempotency.TTL.Duration > 0, "idempotency.ttl must be positive")
+	if c.RateLimits.Enabled {
+		for _, limit := range []struct {
+			name string
+			RateLimit
+		}{{"read", c.RateLimits.Read}, {"write", c.RateLimits.Write}, {"create", c.RateLimits.Create}, {"public", c.RateLimits.Public}} {
+			check(limit.Requests > 0 && limit.Per.Duration > 0 && limit.Burst > 0,
+				"rateLimits.%s must have positive requests, per and burst (RATE_LIMIT_%s)", limit.name, strings.ToUpper(limit.name))
+		}
+	}
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "logging.level %q must be debug, info, warn or error (LOG_LEVEL or -log-level)", c.Logging.Level)
+	}
+	check(c.Logging.Format == "json" || c.Logging.Format == "text", "logging.format %q must be json or text (LOG_FORMAT)", c.Logging.Format)
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		check(c.Tracing.File != "", "tracing.file is required with the file exporter (TRACING_FILE)")
+	default:
+		check(false, "tracing.exporter %q must be none, stdout, file or otlp (TRACING_EXPORTER)", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio must be between 0 and 1")
+
+	return errors.Join(errs...)
+}
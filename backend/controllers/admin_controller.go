@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+
+	"backend/lobbycode"
+	"backend/logging"
+	"backend/model"
+	"backend/validate"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminGameSummary is a condensed view of a game used when listing games
+type AdminGameSummary struct {
+	GameID    string `json:"gameID"`
+	LobbyCode string `json:"lobbyCode"`
+	Status    string `json:"status"`
+	Players   int    `json:"players"`
+	Turn      int    `json:"turn"`
+	Pot       int    `json:"pot"`
+}
+
+// AdminGameResponse is the full state of a game together with its moderation history
+type AdminGameResponse struct {
+	Game  *model.Game         `json:"game"`
+	Audit []*model.AuditEntry `json:"audit"`
+}
+
+// adminActionRequest is the optional body accepted by admin actions
+type adminActionRequest struct {
+	Reason string `json:"Reason" validate:"max=500"`
+	Turn   *int   `json:"Turn,omitempty" validate:"min=0"`
+}
+
+// loadGame retrieves a game by ID, returning a 404 error if it does not exist
+func loadGame(ctx context.Context, dbClient *db.Client, gameID string) (*model.Game, error) {
+	var game *model.Game
+	if err := dbClient.NewRef("games/"+gameID).Get(ctx, &game); err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve game from Firebase RTDB")
+	}
+	if game == nil {
+		return nil, model.ErrGameNotFound
+	}
+	game.GameID = gameID
+	return game, nil
+}
+
+// auditWrite returns the write adding an admin action to the audit log of the game
+func auditWrite(c *fiber.Ctx, action, gameID, reason, detail string) (gameWrites, error) {
+	adminUID, _ := c.Locals("user").(string)
+	entry := &model.AuditEntry{
+		Action:   action,
+		GameID:   gameID,
+		AdminUID: adminUID,
+		Reason:   reason,
+		Detail:   detail,
+		At:       time.Now().UTC(),
+	}
+	// The key is made here rather than by a push, so the entry can be written together with the game.
+	// The time in front keeps the keys of a game in the order of its actions.
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to create audit log key")
+	}
+	key := fmt.Sprintf("%013d-%s", entry.At.UnixMilli(), hex.EncodeToString(suffix))
+	return gameWrites{"auditLog/" + gameID + "/" + key: entry}, nil
+}
+
+// readAudit returns the audit entries of a game, oldest first
+func readAudit(ctx context.Context, dbClient *db.Client, gameID string) ([]*model.AuditEntry, error) {
+	var entries map[string]*model.AuditEntry
+	if err := dbClient.NewRef("auditLog/"+gameID).Get(ctx, &entries); err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve audit log from Firebase RTDB")
+	}
+	audit := make([]*model.AuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		audit = append(audit, entry)
+	}
+	sort.Slice(audit, func(i, j int) bool { return audit[i].At.Before(audit[j].At) })
+	return audit, nil
+}
+
+// adminUpdateGame applies an action to a game through its actor and records the action in the audit log
+func adminUpdateGame(c *fiber.Ctx, dbClient *db.Client, action string, apply func(game *model.Game, req *adminActionRequest) (string, error)) error {
+	gameID := c.Params("gameID")
+
+	req := &adminActionRequest{}
+	if len(c.Body()) > 0 {
+		if err := validate.Body(c, req); err != nil {
+			return err
+		}
+	}
+
+	// The audit entry is saved in the same write as the game, so no action goes unrecorded
+	game, err := updateGameWith(c.UserContext(), dbClient, gameID, func(ctx context.Context, game *model.Game) (gameWrites, error) {
+		detail, err := apply(game, req)
+		if err != nil {
+			return nil, err
+		}
+		return auditWrite(c, action, gameID, req.Reason, detail)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"game": game,
+	})
+}
+
+// AdminListGames lists games matching the given filters
+// @Summary List games
+// @Description Lists games from the Firebase Realtime Database, optionally filtered by status, lobby code or player user ID
+// @Tags Admin
+// @Produce json
+// @Param status query string false "lobby, in_progress, over or abandoned"
+// @Param lobbyCode query string false "Lobby code"
+// @Param userID query string false "User ID of a player in the game"
+// @Param limit query int false "Maximum number of games to return"
+// @Success 200 {array} AdminGameSummary
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/admin/games [get]
+func AdminListGames(c *fiber.Ctx, dbClient *db.Client) error {
+	status := c.Query("status")
+	lobbyCode := lobbycode.Normalize(c.Query("lobbyCode"))
+	userID := c.Query("userID")
+	limit := c.QueryInt("limit", 100)
+
+	var games map[string]*model.Game
+	if err := dbClient.NewRef("games").Get(c.UserContext(), &games); err != nil {
+		logging.FromCtx(c).Error("Failed to retrieve games from Firebase RTDB", logging.KeyError, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve games from Firebase RTDB")
+	}
+
+	summaries := []*AdminGameSummary{}
+	for gameID, game := range games {
+		if status != "" && game.Status() != status {
+			continue
+		}
+		if lobbyCode != "" && lobbycode.Normalize(game.LobbyCode) != lobbyCode {
+			continue
+		}
+		if userID != "" && !game.HasUser(userID) {
+			continue
+		}
+		summaries = append(summaries, &AdminGameSummary{
+			GameID:    gameID,
+			LobbyCode: game.LobbyCode,
+			Status:    game.Status(),
+			Players:   len(game.Players),
+			Turn:      game.Turn,
+			Pot:       game.Pot,
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].GameID < summaries[j].GameID })
+	if len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+
+	return c.JSON(summaries)
+}
+
+// AdminGetGame retrieves the full state and audit history of a game
+// @Summary Get game with history
+// @Description Retrieves the full state of a game, including its turn history and the admin audit log
+// @Tags Admin
+// @Produce json
+// @Param gameID path string true "Game ID"
+// @Success 200 {object} AdminGameResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/admin/games/{gameID} [get]
+func AdminGetGame(c *fiber.Ctx, dbClient *db.Client) error {
+	gameID := c.Params("gameID")
+
+	game, err := loadGame(c.UserContext(), dbClient, gameID)
+	if err != nil {
+		return err
+	}
+
+	audit, err := readAudit(c.UserContext(), dbClient, gameID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(AdminGameResponse{
+		Game:  game,
+		Audit: audit,
+	})
+}
+
+// AdminForceEndGame ends a game, awarding the win to the player with the most chips
+// @Summary Force-end a game
+// @Description Ends a game in progress immediately. The player holding the most chips is declared the winner. Lobbies that have not started get 409.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param gameID path string true "Game ID"
+// @Success 200 {object} Game
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /v1/admin/games/{gameID}/force-end [post]
+func AdminForceEndGame(c *fiber.Ctx, dbClient *db.Client) error {
+	return adminUpdateGame(c, dbClient, "forceEnd", func(game *model.Game, req *adminActionRequest) (string, error) {
+		if game.GameOver {
+			return "", model.ErrGameOver
+		}
+		// A lobby has no rolls to decide a winner by, it can only be abandoned
+		if !game.Started {
+			return "", model.ErrGameNotStarted
+		}
+		game.ForceEnd()
+		if game.Winner == nil {
+			return "no winner", nil
+		}
+		return fmt.Sprintf("winner %s", game.Winner.Name), nil
+	})
+}
+
+// AdminAbandonGame ends a game without a winner
+// @Summary Abandon a game
+// @Description Marks the game as abandoned and over, without declaring a winner. Games that are already over get 409.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param gameID path string true "Game ID"
+// @Success 200 {object} Game
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /v1/admin/games/{gameID}/abandon [post]
+func AdminAbandonGame(c *fiber.Ctx, dbClient *db.Client) error {
+	return adminUpdateGame(c, dbClient, "abandon", func(game *model.Game, req *adminActionRequest) (string, error) {
+		// Abandoning a finished game would erase its winner
+		if game.GameOver {
+			return "", model.ErrGameOver
+		}
+		game.Abandon()
+		return "", nil
+	})
+}
+
+// AdminRemovePlayer removes a player from a game
+// @Summary Remove a player
+// @Description Removes the named player from the game. During a game their chips are moved to the pot. In a lobby they leave as if they had left themselves, and the next human player takes over as host if they hosted.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param gameID path string true "Game ID"
+// @Param playerName path string true "Player name"
+// @Success 200 {object} Game
+// @Failure 404 {object} ErrorResponse
+// @Router /v1/admin/games/{gameID}/players/{playerName} [delete]
+func AdminRemovePlayer(c *fiber.Ctx, dbClient *db.Client) error {
+	playerName := c.Params("playerName")
+	return adminUpdateGame(c, dbClient, "removePlayer", func(game *model.Game, req *adminActionRequest) (string, error) {
+		var err error
+		if game.IsOpenLobby() {
+			err = game.LeaveLobby(playerName)
+		} else {
+			err = game.RemovePlayer(playerName)
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("removed %s", playerName), nil
+	})
+}
+
+// AdminResetTurn resets a stuck turn
+// @Summary Reset the current turn
+// @Description Hands the turn to the player at the given index (defaults to the current turn) and clears the last roll
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param gameID path string true "Game ID"
+// @Success 200 {object} Game
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /v1/admin/games/{gameID}/reset-turn [post]
+func AdminResetTurn(c *fiber.Ctx, dbClient *db.Client) error {
+	return adminUpdateGame(c, dbClient, "resetTurn", func(game *model.Game, req *adminActionRequest) (string, error) {
+		turn := game.Turn
+		if req.Turn != nil {
+			turn = *req.Turn
+		} else if turn >= len(game.Players) {
+			turn = 0
+		}
+		if err := game.ResetTurn(turn); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("turn reset to %d", turn), nil
+	})
+}
+
+// AdminListAudit lists the audit log, optionally for a single game
+// @Summary List audit log
+// @Description Lists admin actions, newest first, optionally restricted to a single game
+// @Tags Admin
+// @Produce json
+// @Param gameID query string false "Game ID"
+// @Success 200 {array} model.AuditEntry
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/admin/audit [get]
+func AdminListAudit(c *fiber.Ctx, dbClient *db.Client) error {
+	if gameID := c.Query("gameID"); gameID != "" {
+		audit, err := readAudit(c.UserContext(), dbClient, gameID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(audit)
+	}
+
+	var auditLog map[string]map[string]*model.AuditEntry
+	if err := dbClient.NewRef("auditLog").Get(c.UserContext(), &auditLog); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve audit log from Firebase RTDB")
+	}
+
+	audit := []*model.AuditEntry{}
+	for _, entries := range auditLog {
+		for _, entry := range entries {
+			audit = append(audit, entry)
+		}
+	}
+	sort.Slice(audit, func(i, j int) bool { return audit[i].At.After(audit[j].At) })
+
+	return c.JSON(audit)
+}
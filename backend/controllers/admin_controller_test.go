@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"backend/model"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTestApp returns an app with the error handler of the server, where requests are made as userID
+func newTestApp(userID string) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler()})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", userID)
+		return c.Next()
+	})
+	return app
+}
+
+// handle adapts a controller to a fiber handler using dbClient
+func handle(dbClient *db.Client, controller func(c *fiber.Ctx, dbClient *db.Client) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return controller(c, dbClient)
+	}
+}
+
+// send makes a request to app and returns the status and body of the response
+func send(t *testing.T, app *fiber.App, req *http.Request) (int, []byte) {
+	t.Helper()
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+// seedGame stores a started game of three players under gameID
+func seedGame(t *testing.T, fake *fakeRTDB, gameID string) *model.Game {
+	t.Helper()
+	players := []*model.Player{
+		{Name: "Ann", Chips: 3, LobbyStatus: true, UserID: "user-ann"},
+		{Name: "Bob", Chips: 3, LobbyStatus: true, UserID: "user-bob"},
+		{Name: "Cat", Chips: 3, LobbyStatus: true, UserID: "user-cat"},
+	}
+	game := &model.Game{
+		Players:   players,
+		Creator:   players[0],
+		Player:    players[0],
+		Dice:      model.NewDice(),
+		LobbyCode: "K7QM2X",
+		Started:   true,
+		Version:   1,
+	}
+	fake.set(t, "games/"+gameID, game)
+	return game
+}
+
+// newAdminApp returns an app serving the admin routes as the admin "admin-1"
+func newAdminApp(dbClient *db.Client) *fiber.App {
+	app := newTestApp("admin-1")
+	app.Get("/admin/games", handle(dbClient, AdminListGames))
+	app.Get("/admin/games/:gameID", handle(dbClient, AdminGetGame))
+	app.Post("/admin/games/:gameID/force-end", handle(dbClient, AdminForceEndGame))
+	app.Post("/admin/games/:gameID/abandon", handle(dbClient, AdminAbandonGame))
+	app.Delete("/admin/games/:gameID/players/:playerName", handle(dbClient, AdminRemovePlayer))
+	app.Post("/admin/games/:gameID/reset-turn", handle(dbClient, AdminResetTurn))
+	app.Get("/admin/audit", handle(dbClient, AdminListAudit))
+	return app
+}
+
+// seedAdminGames stores a lobby, a game in progress, a finished game and an abandoned one
+func seedAdminGames(t *testing.T, fake *fakeRTDB) {
+	t.Helper()
+	seedGame(t, fake, "admin-playing")
+
+	lobby := seedGame(t, fake, "admin-lobby")
+	lobby.Started = false
+	lobby.LobbyCode = "LOBBY2"
+	fake.set(t, "games/admin-lobby", lobby)
+
+	over := seedGame(t, fake, "admin-over")
+	over.GameOver = true
+	over.Winner = over.Players[1]
+	over.LobbyCode = "OVER23"
+	fake.set(t, "games/admin-over", over)
+
+	abandoned := seedGame(t, fake, "admin-abandoned")
+	abandoned.Abandon()
+	abandoned.LobbyCode = "GONE23"
+	fake.set(t, "games/admin-abandoned", abandoned)
+}
+
+func TestAdminListGames(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedAdminGames(t, fake)
+	fake.set(t, "games/admin-stranger", &model.Game{
+		Players:   []*model.Player{{Name: "Zed", Chips: 3, UserID: "user-zed"}},
+		LobbyCode: "ZED234",
+	})
+	app := newAdminApp(dbClient)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: []string{"admin-abandoned", "admin-lobby", "admin-over", "admin-playing", "admin-stranger"}},
+		{query: "?status=in_progress", want: []string{"admin-playing"}},
+		{query: "?status=abandoned", want: []string{"admin-abandoned"}},
+		{query: "?lobbyCode=%20lobby2", want: []string{"admin-lobby"}},
+		{query: "?userID=user-zed", want: []string{"admin-stranger"}},
+		{query: "?userID=user-nobody", want: []string{}},
+		{query: "?limit=2", want: []string{"admin-abandoned", "admin-lobby"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			status, body := send(t, app, httptest.NewRequest(fiber.MethodGet, "/admin/games"+tt.query, nil))
+			if status != fiber.StatusOK {
+				t.Fatalf("got status %d: %s", status, body)
+			}
+			var summaries []*AdminGameSummary
+			if err := json.Unmarshal(body, &summaries); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, summary := range summaries {
+				got = append(got, summary.GameID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("got games %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminActions(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		// check inspects the stored game after a successful action
+		check func(t *testing.T, game *model.Game)
+	}{
+		{
+			name: "force-end a game in progress", method: fiber.MethodPost, path: "/admin/games/admin-playing/force-end",
+			body: `{"Reason":"stuck"}`, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, game *model.Game) {
+				if !game.GameOver || game.Abandoned || game.Winner == nil {
+					t.Errorf("got over %v abandoned %v winner %v, want over with a winner", game.GameOver, game.Abandoned, game.Winner)
+				}
+			},
+		},
+		{
+			name: "force-end a lobby", method: fiber.MethodPost, path: "/admin/games/admin-lobby/force-end",
+			wantStatus: fiber.StatusConflict,
+		},
+		{
+			name: "force-end a finished game", method: fiber.MethodPost, path: "/admin/games/admin-over/force-end",
+			wantStatus: fiber.StatusConflict,
+		},
+		{
+			name: "force-end a missing game", method: fiber.MethodPost, path: "/admin/games/admin-missing/force-end",
+			wantStatus: fiber.StatusNotFound,
+		},
+		{
+			name: "abandon a game in progress", method: fiber.MethodPost, path: "/admin/games/admin-playing/abandon",
+			wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, game *model.Game) {
+				if !game.GameOver || !game.Abandoned || game.Winner != nil {
+					t.Errorf("got over %v abandoned %v winner %v, want abandoned without a winner", game.GameOver, game.Abandoned, game.Winner)
+				}
+			},
+		},
+		{
+			name: "abandon an abandoned game", method: fiber.MethodPost, path: "/admin/games/admin-abandoned/abandon",
+			wantStatus: fiber.StatusConflict,
+		},
+		{
+			name: "abandon a finished game", method: fiber.MethodPost, path: "/admin/games/admin-over/abandon",
+			wantStatus: fiber.StatusConflict,
+		},
+		{
+			name: "remove a player from a game in progress", method: fiber.MethodDelete, path: "/admin/games/admin-playing/players/Bob",
+			wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, game *model.Game) {
+				if len(game.Players) != 2 || game.Players[1].Name != "Cat" || game.Pot != 3 {
+					t.Errorf("got %d players and pot %d, want Bob gone and his chips in the pot", len(game.Players), game.Pot)
+				}
+			},
+		},
+		{
+			name: "remove the host from a lobby", method: fiber.MethodDelete, path: "/admin/games/admin-lobby/players/Ann",
+			wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, game *model.Game) {
+				if len(game.Players) != 2 || game.Pot != 0 || game.Creator == nil || game.Creator.Name != "Bob" {
+					t.Errorf("got %d players, pot %d and host %v, want Ann gone with their chips and Bob hosting", len(game.Players), game.Pot, game.Creator)
+				}
+			},
+		},
+		{
+			name: "remove an unknown player", method: fiber.MethodDelete, path: "/admin/games/admin-playing/players/Eve",
+			wantStatus: fiber.StatusNotFound,
+		},
+		{
+			name: "reset the turn", method: fiber.MethodPost, path: "/admin/games/admin-playing/reset-turn",
+			body: `{"Turn":2}`, wantStatus: fiber.StatusOK,
+			check: func(t *testing.T, game *model.Game) {
+				if game.Turn != 2 || game.Player == nil || game.Player.Name != "Cat" {
+					t.Errorf("got turn %d, want the turn of Cat", game.Turn)
+				}
+			},
+		},
+		{
+			name: "reset the turn out of range", method: fiber.MethodPost, path: "/admin/games/admin-playing/reset-turn",
+			body: `{"Turn":7}`, wantStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbClient, fake := newTestDB(t)
+			seedAdminGames(t, fake)
+			app := newAdminApp(dbClient)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			status, body := send(t, app, req)
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", status, tt.wantStatus, body)
+			}
+
+			gameID := strings.Split(tt.path, "/")[3]
+			var audit map[string]*model.AuditEntry
+			fake.get(t, "auditLog/"+gameID, &audit)
+			if tt.check == nil {
+				if len(audit) != 0 {
+					t.Fatalf("failed action was audited: %v", audit)
+				}
+				return
+			}
+
+			var game model.Game
+			fake.get(t, "games/"+gameID, &game)
+			tt.check(t, &game)
+			if len(audit) != 1 {
+				t.Fatalf("got %d audit entries, want 1", len(audit))
+			}
+			for _, entry := range audit {
+				if entry.GameID != gameID || entry.AdminUID != "admin-1" {
+					t.Errorf("got audit entry %+v, want one by admin-1 on %s", entry, gameID)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminActionAuditFails(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedAdminGames(t, fake)
+	fake.failWrites("auditLog")
+	app := newAdminApp(dbClient)
+
+	status, body := send(t, app, httptest.NewRequest(fiber.MethodPost, "/admin/games/admin-playing/abandon", nil))
+	if status != fiber.StatusInternalServerError {
+		t.Fatalf("got status %d, want 500: %s", status, body)
+	}
+	var game model.Game
+	fake.get(t, "games/admin-playing", &game)
+	if game.GameOver || game.Abandoned {
+		t.Fatal("game was abandoned without an audit entry")
+	}
+}
+
+func TestAdminGetGameAndAudit(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedAdminGames(t, fake)
+	app := newAdminApp(dbClient)
+
+	for _, path := range []string{"/admin/games/admin-playing/reset-turn", "/admin/games/admin-playing/force-end", "/admin/games/admin-lobby/abandon"} {
+		req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(`{"Reason":"test"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if status, body := send(t, app, req); status != fiber.StatusOK {
+			t.Fatalf("%s: got status %d: %s", path, status, body)
+		}
+	}
+
+	status, body := send(t, app, httptest.NewRequest(fiber.MethodGet, "/admin/games/admin-playing", nil))
+	if status != fiber.StatusOK {
+		t.Fatalf("got status %d: %s", status, body)
+	}
+	var got AdminGameResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Game == nil || !got.Game.GameOver || len(got.Audit) != 2 || got.Audit[0].Action != "resetTurn" || got.Audit[1].Action != "forceEnd" {
+		t.Fatalf("got %+v, want the finished game with its two actions, oldest first", got)
+	}
+
+	status, body = send(t, app, httptest.NewRequest(fiber.MethodGet, "/admin/audit", nil))
+	if status != fiber.StatusOK {
+		t.Fatalf("got status %d: %s", status, body)
+	}
+	var audit []*model.AuditEntry
+	if err := json.Unmarshal(body, &audit); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit) != 3 || !sort.SliceIsSorted(audit, func(i, j int) bool { return audit[i].At.After(audit[j].At) }) {
+		t.Fatalf("got %d audit entries, want 3 newest first", len(audit))
+	}
+
+	if status, _ := send(t, app, httptest.NewRequest(fiber.MethodGet, "/admin/games/admin-missing", nil)); status != fiber.StatusNotFound {
+		t.Fatalf("got status %d for a missing game, want 404", status)
+	}
+}
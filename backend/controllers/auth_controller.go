@@ -1,11 +1,12 @@
 package controllers
 
 import (
-	"context"
 	"fmt"
 	"strings"
 
 	"backend/db" // <-- add this
+	"backend/responses"
+	"backend/validate"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -25,21 +26,49 @@ import (
 func AuthRequired() func(*fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
 		// Get token from header
-		bearerToken := c.Get("Authorization")
-		splitToken := strings.Split(bearerToken, "Bearer ")
-		token := splitToken[1]
+		bearerToken := c.Get(fiber.HeaderAuthorization)
+		if fe := validate.Var(validate.InHeader, fiber.HeaderAuthorization, bearerToken, "required,prefix=Bearer "); fe != nil {
+			err := responses.NewError(fiber.StatusUnauthorized, responses.CodeUnauthorized, "Missing or malformed bearer token")
+			err.Fields = []responses.FieldError{*fe}
+			return err
+		}
+		token := strings.TrimPrefix(bearerToken, "Bearer ")
 
 		// Verify the token using your Firebase admin SDK
 
-		tokenInfo, err := db.AuthClient.VerifyIDToken(context.Background(), token) // <-- change this
+		tokenInfo, err := db.AuthClient.VerifyIDToken(c.UserContext(), token) // <-- change this
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).SendString(fmt.Sprintf("Invalid ID token: %v\n", err))
+			return fiber.NewError(fiber.StatusUnauthorized, fmt.Sprintf("Invalid ID token: %v", err))
 		}
 
-		// Set the user ID to context
+		// Set the user ID and custom claims to context
 		c.Locals("user", tokenInfo.UID)
+		c.Locals("claims", tokenInfo.Claims)
 
 		// Call the next handler
 		return c.Next()
 	}
 }
+
+// AdminRequired is a middleware function that only lets through users with the "admin" custom claim
+// or whose UID is in adminUIDs. It must run after AuthRequired.
+func AdminRequired(adminUIDs []string) func(*fiber.Ctx) error {
+	admins := make(map[string]bool)
+	for _, uid := range adminUIDs {
+		admins[uid] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user").(string)
+		if userID == "" {
+			return fiber.NewError(fiber.StatusUnauthorized, "missing user")
+		}
+
+		claims, _ := c.Locals("claims").(map[string]interface{})
+		if isAdmin, _ := claims["admin"].(bool); isAdmin || admins[userID] {
+			return c.Next()
+		}
+
+		return fiber.NewError(fiber.StatusForbidden, "admin access required")
+	}
+}
@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"backend/logging"
+	"backend/metrics"
+	"backend/model"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// actorIdleTimeout is how long the actor of a game waits for a command before it stops. The game is
+// stored after every command, so a stopped actor loses nothing but a running turn timer, which keeps
+// the actor alive. Tests shorten it.
+var actorIdleTimeout = 5 * time.Minute
+
+// errTurnNotExpired stops an expired turn from being played when the game has changed since the turn
+// timer was started
+var errTurnNotExpired = errors.New("turn has not expired")
+
+// gameActors holds the running actor of each active game, keyed by game ID
+var gameActors sync.Map
+
+// gameActor owns one game on this server. It runs the commands sent to the game one at a time, so the
+// read-modify-write of a command never interleaves with another command on the same game.
+type gameActor struct {
+	gameID   string
+	commands chan *gameCommand
+	// done is closed once the actor has stopped taking commands
+	done chan struct{}
+	// stopping is set by a command asking the actor to stop after it
+	stopping bool
+	// idleTimeout is actorIdleTimeout as it was when the actor started
+	idleTimeout time.Duration
+	// turnTimer fires once the current player of the game runs out of time to roll. It is nil while no
+	// turn timer runs.
+	turnTimer *time.Timer
+	// dbClient is the client an expired turn is played with
+	dbClient *db.Client
+}
+
+// gameCommand is a function run by the actor of a game, with done closed once it has run
+type gameCommand struct {
+	run  func(a *gameActor)
+	done chan struct{}
+	// panicked holds what run panicked with, so the panic surfaces in the goroutine that sent the command
+	panicked interface{}
+}
+
+// actorFor returns the running actor of the game, starting one if there is none
+func actorFor(gameID string) *gameActor {
+	if a, ok := gameActors.Load(gameID); ok {
+		return a.(*gameActor)
+	}
+	a := &gameActor{
+		gameID:      gameID,
+		commands:    make(chan *gameCommand),
+		done:        make(chan struct{}),
+		idleTimeout: actorIdleTimeout,
+	}
+	actual, loaded := gameActors.LoadOrStore(gameID, a)
+	if !loaded {
+		go a.loop()
+	}
+	return actual.(*gameActor)
+}
+
+// loop runs the commands of the game until the actor goes idle or is asked to stop
+func (a *gameActor) loop() {
+	defer close(a.done)
+	defer gameActors.CompareAndDelete(a.gameID, a)
+
+	defer a.stopTurnTimer()
+
+	idle := time.NewTimer(a.idleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case cmd := <-a.commands:
+			a.exec(cmd)
+			if a.stopping {
+				return
+			}
+		case <-a.turnExpired():
+			cmd := &gameCommand{run: (*gameActor).expireTurn, done: make(chan struct{})}
+			if a.exec(cmd); cmd.panicked != nil {
+				slog.Error("Playing an expired turn panicked", logging.KeyGameID, a.gameID, "panic", cmd.panicked)
+			}
+		case <-idle.C:
+			// A running turn timer keeps the actor alive, as nobody else would play the expired turn
+			if a.turnTimer != nil {
+				idle.Reset(a.idleTimeout)
+				continue
+			}
+			return
+		}
+		if !idle.Stop() {
+			<-idle.C
+		}
+		idle.Reset(a.idleTimeout)
+	}
+}
+
+// exec runs a command, recovering from a panic so one bad command does not take the actor down
+func (a *gameActor) exec(cmd *gameCommand) {
+	defer close(cmd.done)
+	defer func() {
+		cmd.panicked = recover()
+	}()
+	cmd.run(a)
+}
+
+// sendCommand runs run inside the actor of the game and waits for it to finish. A command sent to an
+// actor that is stopping goes to the actor started after it.
+func sendCommand(ctx context.Context, gameID string, run func(a *gameActor)) error {
+	cmd := &gameCommand{run: run, done: make(chan struct{})}
+	for {
+		a := actorFor(gameID)
+		select {
+		case a.commands <- cmd:
+			<-cmd.done
+			if cmd.panicked != nil {
+				panic(cmd.panicked)
+			}
+			return nil
+		case <-a.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// gameWrites are further writes saved together with a game, keyed by their path from the root
+type gameWrites map[string]interface{}
+
+// updateGame applies a change to the latest state of the game inside its actor, then saves the game,
+// wakes its long polls and updates the lobby index and lobby code. Nothing is saved when apply fails.
+func updateGame(ctx context.Context, dbClient *db.Client, gameID string, apply func(ctx context.Context, game *model.Game) error) (*model.Game, error) {
+	return updateGameWith(ctx, dbClient, gameID, func(ctx context.Context, game *model.Game) (gameWrites, error) {
+		return nil, apply(ctx, game)
+	})
+}
+
+// updateGameWith is updateGame for changes that write more than the game. The writes returned by apply
+// are saved in one multi-path update with the game, so either all of them are saved or none is.
+func updateGameWith(ctx context.Context, dbClient *db.Client, gameID string, apply func(ctx context.Context, game *model.Game) (gameWrites, error)) (*model.Game, error) {
+	var game *model.Game
+	var err error
+	if serr := sendCommand(ctx, gameID, func(a *gameActor) {
+		game, err = a.updateWith(ctx, dbClient, apply)
+	}); serr != nil {
+		return nil, serr
+	}
+	return game, err
+}
+
+// UpdateGame is updateGame for the packages outside controllers, such as the janitor
+func UpdateGame(ctx context.Context, dbClient *db.Client, gameID string, apply func(ctx context.Context, game *model.Game) error) (*model.Game, error) {
+	return updateGame(ctx, dbClient, gameID, apply)
+}
+
+// update runs one read-modify-write of the game
+func (a *gameActor) update(ctx context.Context, dbClient *db.Client, apply func(ctx context.Context, game *model.Game) error) (*model.Game, error) {
+	return a.updateWith(ctx, dbClient, func(ctx context.Context, game *model.Game) (gameWrites, error) {
+		return nil, apply(ctx, game)
+	})
+}
+
+// updateWith runs one read-modify-write of the game, saving the writes returned by apply with it
+func (a *gameActor) updateWith(ctx context.Context, dbClient *db.Client, apply func(ctx context.Context, game *model.Game) (gameWrites, error)) (*model.Game, error) {
+	game, err := loadGame(ctx, dbClient, a.gameID)
+	if err != nil {
+		return nil, err
+	}
+	wasLobby := game.IsOpenLobby()
+
+	writes, err := apply(ctx, game)
+	if err != nil {
+		return nil, err
+	}
+
+	game.Touch()
+	if len(writes) == 0 {
+		err = dbClient.NewRef("games/"+a.gameID).Set(ctx, game)
+	} else {
+		writes["games/"+a.gameID] = game
+		err = dbClient.NewRef("/").Update(ctx, writes)
+	}
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to save updated game to Firebase RTDB")
+	}
+	notifyGameChanged(game)
+	a.startTurnTimer(dbClient, game)
+
+	if wasLobby || game.IsOpenLobby() {
+		if err := UpdateLobbyIndex(ctx, dbClient, a.gameID, game); err != nil {
+			slog.ErrorContext(ctx, "Failed to update lobby index", logging.KeyGameID, a.gameID, logging.KeyError, err)
+		}
+	}
+	ReleaseLobbyCode(ctx, a.gameID, game)
+	return game, nil
+}
+
+// startTurnTimer starts the turn timer of the game as it was just saved, replacing the previous one
+func (a *gameActor) startTurnTimer(dbClient *db.Client, game *model.Game) {
+	a.stopTurnTimer()
+	deadline, ok := game.TurnDeadline()
+	if !ok {
+		return
+	}
+	a.dbClient = dbClient
+	a.turnTimer = time.NewTimer(time.Until(deadline))
+}
+
+// stopTurnTimer stops the turn timer, if one runs
+func (a *gameActor) stopTurnTimer() {
+	if a.turnTimer != nil {
+		a.turnTimer.Stop()
+		a.turnTimer = nil
+	}
+}
+
+// turnExpired returns the channel of the turn timer, or nil, which never receives, when no timer runs
+func (a *gameActor) turnExpired() <-chan time.Time {
+	if a.turnTimer == nil {
+		return nil
+	}
+	return a.turnTimer.C
+}
+
+// expireTurn plays the turn of a player who let the turn timer run out
+func (a *gameActor) expireTurn() {
+	a.turnTimer = nil
+	ctx := context.Background()
+
+	var latest *model.Game
+	game, err := a.update(ctx, a.dbClient, func(ctx context.Context, game *model.Game) error {
+		latest = game
+		// Another server may have moved the game on since the timer was started
+		if deadline, ok := game.TurnDeadline(); !ok || time.Now().Before(deadline) {
+			return errTurnNotExpired
+		}
+		playTurn(ctx, a.gameID, game)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errTurnNotExpired):
+		a.startTurnTimer(a.dbClient, latest)
+	case err != nil:
+		slog.ErrorContext(ctx, "Failed to play expired turn", logging.KeyGameID, a.gameID, logging.KeyError, err)
+	default:
+		metrics.TurnPlayed()
+		if game.GameOver && game.Winner != nil {
+			metrics.GameFinished(len(game.Players), game.Winner.IsBot())
+		}
+	}
+}
+
+// ExpireTurn plays the current turn of the game if its turn timer has run out, and otherwise starts the
+// timer. The janitor uses it to resume the turn timers of games whose actor has stopped, e.g. on a restart.
+func ExpireTurn(ctx context.Context, dbClient *db.Client, gameID string) error {
+	return sendCommand(ctx, gameID, func(a *gameActor) {
+		a.stopTurnTimer()
+		a.dbClient = dbClient
+		a.expireTurn()
+	})
+}
+
+// GameActorIDs returns the IDs of the games with a running actor
+func GameActorIDs() []string {
+	var ids []string
+	gameActors.Range(func(key, _ interface{}) bool {
+		ids = append(ids, key.(string))
+		return true
+	})
+	return ids
+}
+
+// StopGameActor stops the actor of the game once the commands sent before have run, dropping what it
+// holds in memory
+func StopGameActor(ctx context.Context, gameID string) error {
+	a, ok := gameActors.Load(gameID)
+	if !ok {
+		return nil
+	}
+	cmd := &gameCommand{run: func(a *gameActor) { a.stopping = true }, done: make(chan struct{})}
+	select {
+	case a.(*gameActor).commands <- cmd:
+		<-cmd.done
+		return nil
+	case <-a.(*gameActor).done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stop actor of game %s: %w", gameID, ctx.Err())
+	}
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/model"
+)
+
+func TestUpdateGameSerializesCommands(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedGame(t, fake, "actor-serial")
+
+	const updates = 20
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := updateGame(context.Background(), dbClient, "actor-serial", func(ctx context.Context, game *model.Game) error {
+				game.Pot++
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var game model.Game
+	fake.get(t, "games/actor-serial", &game)
+	if game.Pot != updates || game.Version != 1+updates {
+		t.Fatalf("got pot %d version %d, want pot %d version %d", game.Pot, game.Version, updates, 1+updates)
+	}
+}
+
+func TestUpdateGameKeepsFailedChangesOut(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedGame(t, fake, "actor-failed")
+
+	_, err := updateGame(context.Background(), dbClient, "actor-failed", func(ctx context.Context, game *model.Game) error {
+		game.Pot = 99
+		return model.ErrNotYourTurn
+	})
+	if !errors.Is(err, model.ErrNotYourTurn) {
+		t.Fatalf("got error %v, want %v", err, model.ErrNotYourTurn)
+	}
+
+	var game model.Game
+	fake.get(t, "games/actor-failed", &game)
+	if game.Pot != 0 || game.Version != 1 {
+		t.Fatalf("got pot %d version %d, want the game unchanged", game.Pot, game.Version)
+	}
+}
+
+func TestUpdateGameMissingGame(t *testing.T) {
+	dbClient, _ := newTestDB(t)
+	_, err := updateGame(context.Background(), dbClient, "actor-missing", func(ctx context.Context, game *model.Game) error {
+		return nil
+	})
+	if !errors.Is(err, model.ErrGameNotFound) {
+		t.Fatalf("got error %v, want %v", err, model.ErrGameNotFound)
+	}
+}
+
+func TestSendCommandPanic(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedGame(t, fake, "actor-panic")
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Fatalf("recovered %v, want the panic of the command", recovered)
+			}
+		}()
+		_ = sendCommand(context.Background(), "actor-panic", func(a *gameActor) {
+			panic("boom")
+		})
+	}()
+
+	// The actor keeps taking commands after one panicked
+	if _, err := updateGame(context.Background(), dbClient, "actor-panic", func(ctx context.Context, game *model.Game) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStopGameActor(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedGame(t, fake, "actor-stop")
+
+	if _, err := updateGame(context.Background(), dbClient, "actor-stop", func(ctx context.Context, game *model.Game) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, ok := gameActors.Load("actor-stop")
+	if !ok || !containsID(GameActorIDs(), "actor-stop") {
+		t.Fatal("game has no running actor after an update")
+	}
+
+	if err := StopGameActor(context.Background(), "actor-stop"); err != nil {
+		t.Fatal(err)
+	}
+	<-a.(*gameActor).done
+	if containsID(GameActorIDs(), "actor-stop") {
+		t.Fatal("stopped actor is still listed")
+	}
+
+	// The next command starts a new actor
+	if _, err := updateGame(context.Background(), dbClient, "actor-stop", func(ctx context.Context, game *model.Game) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTurnTimer(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	game := seedGame(t, fake, "actor-timer")
+	game.Settings.TurnTimeout = 1
+	fake.set(t, "games/actor-timer", game)
+	t.Cleanup(func() { StopGameActor(context.Background(), "actor-timer") })
+
+	// Saving the game starts the timer of the player on turn
+	if _, err := updateGame(context.Background(), dbClient, "actor-timer", func(ctx context.Context, game *model.Game) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		fake.get(t, "games/actor-timer", game)
+		if len(game.History) > 0 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the expired turn was never played")
+		}
+	}
+	if game.History[0].Player != "Ann" || game.Turn != 1 {
+		t.Fatalf("got turn %d after %s played, want the turn of Ann played", game.Turn, game.History[0].Player)
+	}
+}
+
+func TestExpireTurn(t *testing.T) {
+	tests := []struct {
+		name        string
+		turnTimeout int
+		idleFor     time.Duration
+		wantPlayed  bool
+	}{
+		{name: "turn ran out", turnTimeout: 30, idleFor: time.Minute, wantPlayed: true},
+		{name: "turn still running", turnTimeout: 30, idleFor: 10 * time.Second},
+		{name: "no turn timer", idleFor: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbClient, fake := newTestDB(t)
+			game := seedGame(t, fake, "actor-expire")
+			game.Settings.TurnTimeout = tt.turnTimeout
+			game.UpdatedAt = time.Now().Add(-tt.idleFor)
+			fake.set(t, "games/actor-expire", game)
+			t.Cleanup(func() { StopGameActor(context.Background(), "actor-expire") })
+
+			if err := ExpireTurn(context.Background(), dbClient, "actor-expire"); err != nil {
+				t.Fatal(err)
+			}
+			fake.get(t, "games/actor-expire", game)
+			if played := len(game.History) > 0; played != tt.wantPlayed {
+				t.Fatalf("got turn played %v, want %v", played, tt.wantPlayed)
+			}
+		})
+	}
+}
+
+func TestActorIdleTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		turnTimeout int
+		wantStopped bool
+	}{
+		{name: "idle actor stops", wantStopped: true},
+		{name: "running turn timer keeps the actor", turnTimeout: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(timeout time.Duration) { actorIdleTimeout = timeout }(actorIdleTimeout)
+			actorIdleTimeout = 20 * time.Millisecond
+
+			dbClient, fake := newTestDB(t)
+			game := seedGame(t, fake, "actor-idle")
+			game.Settings.TurnTimeout = tt.turnTimeout
+			fake.set(t, "games/actor-idle", game)
+			t.Cleanup(func() { StopGameActor(context.Background(), "actor-idle") })
+
+			if _, err := updateGame(context.Background(), dbClient, "actor-idle", func(ctx context.Context, game *model.Game) error {
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			a, ok := gameActors.Load("actor-idle")
+			if !ok {
+				t.Fatal("game has no running actor after an update")
+			}
+
+			select {
+			case <-a.(*gameActor).done:
+				if !tt.wantStopped {
+					t.Fatal("actor stopped while its turn timer was running")
+				}
+			case <-time.After(200 * time.Millisecond):
+				if tt.wantStopped {
+					t.Fatal("idle actor never stopped")
+				}
+			}
+		})
+	}
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
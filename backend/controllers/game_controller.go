@@ -1,52 +1,68 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"math/rand"
-	"time"
 
 	// "backend/db"
 	"firebase.google.com/go/v4/db"
 	// "backend/errors"
-	"backend/lcr"
+	"backend/invite"
+	"backend/lobbycode"
+	"backend/logging"
+	"backend/metrics"
+	"backend/responses"
+	"backend/tracing"
+	"backend/validate"
 
 	"backend/model"
 	// "backend/util"
 
 	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // GetAvailableGamesResponse represents the response structure for the available games endpoint
 type GetAvailableGamesResponse struct {
-	Games map[string]*model.Game `json:"games"`
+	Lobbies    []*model.LobbySummary `json:"lobbies"`
+	NextCursor string                `json:"nextCursor,omitempty"`
 }
-type CreateGameResponse struct {
-	GameID    string        `json:"gameID"`
-	LobbyCode string        `json:"lobbyCode"`
-	Creator   *model.Player `json:"creator"`
+
+// CreateGameRequest represents the request body for the create game endpoint: the players, the lobby
+// settings and an optional password. A bare array of players is also accepted.
+type CreateGameRequest struct {
+	Players  []*PlayerRequest `json:"Players" validate:"required,min=1"`
+	Password string           `json:"Password" validate:"maxbytes=72"`
+	model.Settings
 }
 
-// LCRGames is a map that holds LCR games
-var LCRGames = make(map[string]*lcr.LCRGame)
+// PlayerRequest is a player sent to the create game endpoint. The server sets their chips from the lobby
+// settings, their user and their ready status, so Chips is ignored and LobbyStatus must not be set.
+type PlayerRequest struct {
+	Name        string `json:"Name" validate:"required,max=32"`
+	Chips       int    `json:"Chips" validate:"min=0"`
+	LobbyStatus bool   `json:"LobbyStatus" validate:"forbidden"`
+}
 
-// generateLobbyCode generates a random lobby code
-func GenerateLobbyCode() string {
-	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const codeLength = 5
-	var seededRand *rand.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+// JoinGameRequest represents the request body for the join game endpoint
+type JoinGameRequest struct {
+	Name     string `json:"Name" validate:"required,max=32"`
+	Password string `json:"Password" validate:"maxbytes=72"`
+}
 
-	b := make([]byte, codeLength)
-	for i := range b {
-		b[i] = charset[seededRand.Intn(len(charset))]
-	}
-	return string(b)
+type CreateGameResponse struct {
+	GameID    string        `json:"gameID"`
+	LobbyCode string        `json:"lobbyCode"`
+	Creator   *model.Player `json:"creator"`
 }
 
 // addBotsToGame adds bots to the game
 // @Summary Add bots to game
-// @Description Adds a random number of bots (between 2 and 4) to the game identified by the provided lobby code in the Firebase Realtime Database
+// @Description Adds a random number of bots (between 2 and 4) to the game identified by the provided lobby code in the Firebase Realtime Database, never exceeding MaxPlayers. With BotFill every free seat is filled. Only the host can add bots.
 // @Tags Games
 // @Accept json
 // @Produce json
@@ -56,51 +72,68 @@ func GenerateLobbyCode() string {
 // @Success 200 {object} Game
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /games/add-bots/{lobbyCode} [put]
+// @Failure 409 {object} ErrorResponse
+// @Router /v1/lobbies/{lobbyCode}/bots [post]
 func AddBotsToGame(c *fiber.Ctx, dbClient *db.Client) error {
-	lobbyCode := c.Params("lobbyCode")
-
-	// Generate a random number of bots between 2 and 4
-	numBots := rand.Intn(3) + 2
-
-	// Find game with the given lobby code
-	gamesRef := dbClient.NewRef("games")
-	query := gamesRef.OrderByChild("LobbyCode").EqualTo(lobbyCode).LimitToFirst(1)
-	results, err := query.GetOrdered(context.Background())
+	gameID, err := GetGameIDByLobbyCode(c, dbClient)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to query games from Firebase RTDB")
+		return err
 	}
 
-	if len(results) == 0 {
-		return fiber.NewError(fiber.StatusNotFound, "Game not found")
-	}
+	userID, _ := c.Locals("user").(string)
+	game, err := updateGame(c.UserContext(), dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+		if !game.IsHost(userID) {
+			return responses.NewError(fiber.StatusForbidden, "host_only", "Only the host can add bots")
+		}
+		if !game.IsOpenLobby() {
+			return model.ErrGameStarted
+		}
 
-	gameKey := results[0].Key()
-	gameSnapshot := gamesRef.Child(gameKey)
-	var game model.Game
-	if err := gameSnapshot.Get(context.Background(), &game); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve game from Firebase RTDB")
-	}
+		freeSeats := game.FreeSeats()
+		if freeSeats == 0 {
+			return model.ErrLobbyFull
+		}
 
-	// Add the new bots to the game
-	for i := 0; i < numBots; i++ {
-		botName := fmt.Sprintf("Bot %d", len(game.Players))
-		bot := model.NewPlayer(botName)
-		bot.UserID = "3XW4LgX0jMeo6mwTU9NrE0a2rYN2"
-		game.Players = append(game.Players, bot)
-	}
+		// Fill every free seat with bot fill, otherwise add a random number of bots between 2 and 4
+		numBots := freeSeats
+		if !game.Settings.BotFill {
+			numBots = rand.Intn(3) + 2
+			if numBots > freeSeats {
+				numBots = freeSeats
+			}
+		}
 
-	// Save the game with the new bots back to Firebase RTDB
-	if err := gameSnapshot.Set(context.Background(), &game); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save updated game to Firebase RTDB")
+		// Add the new bots to the game
+		startingChips := game.Settings.WithDefaults().StartingChips
+		for i := 0; i < numBots; i++ {
+			bot := model.NewPlayer(nextBotName(game))
+			bot.Chips = startingChips
+			bot.UserID = model.BotUserID
+			game.Players = append(game.Players, bot)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(game)
 }
 
+// nextBotName returns the first "Bot N" name no player of the game uses. Players leaving the lobby free
+// up names, so the number of players does not give a free one.
+func nextBotName(game *model.Game) string {
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("Bot %d", n)
+		if !game.HasPlayerNamed(name) {
+			return name
+		}
+	}
+}
+
 // setBotsReady sets all bots to ready in the game
 // @Summary Set bots ready
-// @Description Sets all the bots in the game identified by the provided lobby code in the Firebase Realtime Database to ready
+// @Description Sets all the bots in the game identified by the provided lobby code in the Firebase Realtime Database to ready. Only the host can ready them.
 // @Tags Games
 // @Accept json
 // @Produce json
@@ -110,37 +143,26 @@ func AddBotsToGame(c *fiber.Ctx, dbClient *db.Client) error {
 // @Success 200 {object} Game
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /games/bots-ready/{lobbyCode} [put]
+// @Router /v1/lobbies/{lobbyCode}/bots/ready [post]
 func SetBotsReady(c *fiber.Ctx, dbClient *db.Client) error {
-	lobbyCode := c.Params("lobbyCode")
-
-	// Find game with the given lobby code
-	gamesRef := dbClient.NewRef("games")
-	query := gamesRef.OrderByChild("LobbyCode").EqualTo(lobbyCode).LimitToFirst(1)
-	results, err := query.GetOrdered(context.Background())
+	gameID, err := GetGameIDByLobbyCode(c, dbClient)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to query games from Firebase RTDB")
-	}
-
-	if len(results) == 0 {
-		return c.Status(fiber.StatusNotFound).SendString("Game not found")
-	}
-
-	gameKey := results[0].Key()
-	gameSnapshot := gamesRef.Child(gameKey)
-	var game model.Game
-	if err := gameSnapshot.Get(context.Background(), &game); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to retrieve game from Firebase RTDB")
-	}
-
-	// Set everyone to ready
-	for _, player := range game.Players {
-		player.LobbyStatus = true
+		return err
 	}
 
-	// Save the game with the new bots back to Firebase RTDB
-	if err := gameSnapshot.Set(context.Background(), &game); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save updated game to Firebase RTDB")
+	userID, _ := c.Locals("user").(string)
+	game, err := updateGame(c.UserContext(), dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+		if !game.IsHost(userID) {
+			return responses.NewError(fiber.StatusForbidden, "host_only", "Only the host can ready the bots")
+		}
+		// Set everyone to ready
+		for _, player := range game.Players {
+			player.LobbyStatus = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(game)
@@ -158,62 +180,69 @@ func SetBotsReady(c *fiber.Ctx, dbClient *db.Client) error {
 // @Success 200 {string} string "OK"
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /games/id/{lobbyCode} [get]
+// @Router /v1/lobbies/{lobbyCode} [get]
 func GetGameIDByLobbyCode(c *fiber.Ctx, dbClient *db.Client) (string, error) {
-	lobbyCode := c.Params("lobbyCode")
-
-	// Find game with the given lobby code
-	gamesRef := dbClient.NewRef("games")
-	query := gamesRef.OrderByChild("LobbyCode").EqualTo(lobbyCode).LimitToFirst(1)
-	results, err := query.GetOrdered(context.Background())
-	if err != nil {
-		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to query games from Firebase RTDB")
-	}
-
-	if len(results) == 0 {
-		return "", fiber.NewError(fiber.StatusNotFound, "Game not found")
-	}
-
-	gameKey := results[0].Key()
+	lobbyCode := lobbyCodeParam(c)
 
-	return gameKey, nil
+	return gameIDByLobbyCode(c.UserContext(), dbClient, lobbyCode)
 }
 
 // getAvailableGames retrieves the list of available games
 // @Summary Get available games
-// @Description Retrieves the list of available games from the Firebase Realtime Database
+// @Description Retrieves one page of open lobbies from the lobby index in the Firebase Realtime Database
 // @Tags Games
 // @Accept json
 // @Produce json
-// @Param c path string true "Fiber context"
-// @Param dbClient path string true "Database client"
+// @Param cursor query string false "Cursor returned as nextCursor by the previous page"
+// @Param limit query int false "Page size, between 1 and 100 (default 20)"
+// @Param sort query string false "newest (default) or oldest"
+// @Param hasPassword query bool false "Only return lobbies with (true) or without (false) a password"
+// @Param hasSeats query bool false "Only return lobbies with free seats"
+// @Param ruleSet query string false "Only return lobbies using this rule set"
+// @Param minPlayers query int false "Minimum number of players in the lobby"
+// @Param maxPlayers query int false "Maximum number of players in the lobby"
 // @Success 200 {object} GetAvailableGamesResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /available-games [get]
+// @Router /v1/lobbies [get]
 func GetAvailableGames(c *fiber.Ctx, dbClient *db.Client) error {
-	gamesRef := dbClient.NewRef("games")
+	limit := c.QueryInt("limit", 20)
+	newestFirst := c.Query("sort", "newest") == "newest"
+
+	var cursor *lobbyCursor
+	if value := c.Query("cursor"); value != "" {
+		var err error
+		if cursor, err = decodeLobbyCursor(value); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
 
-	var games map[string]*model.Game
-	if err := gamesRef.Get(context.Background(), &games); err != nil {
-		log.Printf("Failed to retrieve games from Firebase RTDB: %s", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve games from Firebase RTDB",
-		})
+	filter := &LobbyFilter{
+		HasSeats:   c.QueryBool("hasSeats"),
+		RuleSet:    c.Query("ruleSet"),
+		MinPlayers: c.QueryInt("minPlayers"),
+		MaxPlayers: c.QueryInt("maxPlayers"),
+	}
+	if c.Query("hasPassword") != "" {
+		hasPassword := c.QueryBool("hasPassword")
+		filter.HasPassword = &hasPassword
 	}
 
-	availableGames := make(map[string]*model.Game)
-	for gameID, game := range games {
-		if !game.GameOver {
-			availableGames[gameID] = game
-		}
+	lobbies, nextCursor, err := queryLobbies(c.UserContext(), dbClient, filter, cursor, limit, newestFirst)
+	if err != nil {
+		logging.FromCtx(c).Error("Failed to retrieve lobbies from Firebase RTDB", logging.KeyError, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve lobbies from Firebase RTDB")
 	}
 
-	return c.JSON(GetAvailableGamesResponse{Games: availableGames})
+	return c.JSON(GetAvailableGamesResponse{
+		Lobbies:    lobbies,
+		NextCursor: nextCursor,
+	})
 }
 
 // CreateGame represents the request structure for the create game endpoint
 // @Summary Create a new game
-// @Description Create a new game with the provided players
+// @Description Create a new game with the provided players. The body is either an array of players or a CreateGameRequest carrying the lobby visibility and an optional password.
 // @Tags Games
 // @Accept json
 // @Produce json
@@ -222,63 +251,91 @@ func GetAvailableGames(c *fiber.Ctx, dbClient *db.Client) error {
 // @Success 200 {object} CreateGameResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /games [post]
+// @Router /v1/games [post]
 func CreateGame(c *fiber.Ctx, dbClient *db.Client) error {
-	var players []*model.Player
-	if err := c.BodyParser(&players); err != nil {
-		log.Printf("Error parsing player data: %s", err)
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid player data")
+	req := &CreateGameRequest{}
+	var err error
+	if body := bytes.TrimSpace(c.Body()); len(body) > 0 && body[0] == '[' {
+		err = c.BodyParser(&req.Players)
+	} else {
+		err = c.BodyParser(req)
+	}
+	if err != nil {
+		logging.FromCtx(c).Warn("Error parsing player data", logging.KeyError, err)
+		return validate.Failed([]responses.FieldError{{In: validate.InBody, Rule: "json", Message: "must be an array of players or a CreateGameRequest"}})
+	}
+	if err := validate.Check(req); err != nil {
+		return err
 	}
 
-	if players == nil {
-		log.Println("Player data is empty")
-		return c.Status(fiber.StatusBadRequest).SendString("Player data is empty")
+	userID, ok := c.Locals("user").(string)
+	if !ok {
+		// Handle the case where the user ID is not a string
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid user ID")
 	}
 
 	// Attach the user ID to each player
-	for _, player := range players {
-		fmt.Println("Player:", player)
-		if userID, ok := c.Locals("user").(string); ok {
-			fmt.Println("User ID:", userID)
-			player.UserID = userID
-		} else {
-			// Handle the case where the user ID is not a string
-			log.Println("Invalid user ID")
-			return c.Status(fiber.StatusBadRequest).SendString("Invalid user ID")
-		}
+	players := make([]*model.Player, len(req.Players))
+	for i, p := range req.Players {
+		players[i] = model.NewPlayer(p.Name)
+		players[i].UserID = userID
 	}
 
+	settings := req.Settings.WithDefaults()
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if len(players) > settings.MaxPlayers {
+		return fmt.Errorf("%w: more players than MaxPlayers", model.ErrInvalidSettings)
+	}
 	// Create game with the provided players
-	game, lcrGame := model.NewGame(players)
+	game := model.NewGame(players, settings)
+	game.HasPassword = req.Password != ""
 
 	// Set the creator of the game
 	game.Creator = players[0]
 
-	// Generate lobby code
-	game.LobbyCode = GenerateLobbyCode()
-
 	// Each player in lobby is set to not ready at start
 	for _, player := range game.Players {
 		player.LobbyStatus = false
 	}
 
 	// Save the game to the Firebase RTDB
-	gameRef, err := dbClient.NewRef("games").Push(context.Background(), nil)
+	gameRef, err := dbClient.NewRef("games").Push(c.UserContext(), nil)
 	if err != nil {
-		log.Printf("Failed to create game reference in Firebase RTDB: %s", err)
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to create game reference in Firebase RTDB")
+		logging.FromCtx(c).Error("Failed to create game reference in Firebase RTDB", logging.KeyError, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create game reference in Firebase RTDB")
 	}
 
 	gameID := gameRef.Key
 	game.GameID = gameID
 
-	if err := gameRef.Set(context.Background(), game); err != nil {
-		log.Printf("Failed to save game to Firebase RTDB: %s", err)
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save game to Firebase RTDB")
+	// Reserve a lobby code that no active game is using
+	lobbyCode, err := LobbyCodes.Reserve(c.UserContext(), gameID)
+	if err != nil {
+		logging.FromCtx(c).Error("Failed to reserve lobby code", logging.KeyError, err)
+		if errors.Is(err, lobbycode.ErrExhausted) {
+			return err
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to reserve lobby code")
+	}
+	game.LobbyCode = lobbyCode
+
+	if game.HasPassword {
+		if err := setLobbyPassword(c.UserContext(), dbClient, gameID, req.Password); err != nil {
+			logging.FromCtx(c).Error("Failed to save lobby password to Firebase RTDB", logging.KeyError, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to save lobby password to Firebase RTDB")
+		}
+	}
+
+	if err := gameRef.Set(c.UserContext(), game); err != nil {
+		logging.FromCtx(c).Error("Failed to save game to Firebase RTDB", logging.KeyError, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save game to Firebase RTDB")
 	}
 
-	// Initialize LCRGame
-	LCRGames[gameID] = lcrGame
+	if err := UpdateLobbyIndex(c.UserContext(), dbClient, gameID, game); err != nil {
+		logging.FromCtx(c).Error("Failed to update lobby index", logging.KeyGameID, gameID, logging.KeyError, err)
+	}
 
 	return c.JSON(CreateGameResponse{
 		GameID:    gameID,
@@ -289,7 +346,7 @@ func CreateGame(c *fiber.Ctx, dbClient *db.Client) error {
 
 // joinGame allows a player to join an existing game
 // @Summary Join a game
-// @Description Join an existing game with the provided lobby code
+// @Description Join an existing game with the provided lobby code or invite token. Password-protected lobbies require the Password field in the body unless an invite is used, and private lobbies without a password can only be joined with an invite.
 // @Tags Games
 // @Accept json
 // @Produce json
@@ -298,52 +355,130 @@ func CreateGame(c *fiber.Ctx, dbClient *db.Client) error {
 // @Param lobbyCode path string true "Lobby code"
 // @Success 200 {object} Game
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /games/{lobbyCode}/join [post]
+// @Router /v1/lobbies/{lobbyCode}/players [post]
 func JoinGame(c *fiber.Ctx, dbClient *db.Client) error {
-	lobbyCode := c.Params("lobbyCode")
+	lobbyCode := lobbyCodeParam(c)
+
+	// An invite token may be used in place of the lobby code
+	var claims *invite.Claims
+	if param := c.Params("lobbyCode"); invite.LooksLikeToken(param) {
+		var err error
+		if claims, err = Invites.Verify(param); err != nil {
+			return err
+		}
+		lobbyCode = claims.LobbyCode
+	}
 
-	// Find game with the given lobby code
-	gamesRef := dbClient.NewRef("games")
-	query := gamesRef.OrderByChild("LobbyCode").EqualTo(lobbyCode).LimitToFirst(1)
-	results, err := query.GetOrdered(context.Background())
+	gameKey, err := gameIDByLobbyCode(c.UserContext(), dbClient, lobbyCode)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to query games from Firebase RTDB")
+		return err
 	}
-
-	if len(results) == 0 {
-		return c.Status(fiber.StatusNotFound).SendString("Game not found")
+	if claims != nil && claims.GameID != gameKey {
+		// The lobby code has since been recycled for another game
+		return invite.ErrExpired
 	}
 
-	gameKey := results[0].Key()
-	gameSnapshot := gamesRef.Child(gameKey)
-	var game model.Game
-	if err := gameSnapshot.Get(context.Background(), &game); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to retrieve game from Firebase RTDB")
+	playerData := &JoinGameRequest{}
+	if err := validate.Body(c, playerData); err != nil {
+		return err
 	}
 
-	var playerData struct {
-		Name string `json:"Name"`
+	userID := c.Locals("user").(string)
+	join := func(ctx context.Context, game *model.Game) error {
+		if !game.IsOpenLobby() {
+			return model.ErrGameStarted
+		}
+		if game.HasUser(userID) {
+			return model.ErrAlreadyInGame
+		}
+		if game.FreeSeats() == 0 {
+			return model.ErrLobbyFull
+		}
+		if game.HasPlayerNamed(playerData.Name) {
+			return fmt.Errorf("%w: %s", model.ErrNameTaken, playerData.Name)
+		}
+
+		// Users holding an invite skip the password
+		switch {
+		case claims != nil:
+			if err := invite.CheckUnused(ctx, dbClient, claims, userID); err != nil {
+				if errors.Is(err, invite.ErrUsed) {
+					return err
+				}
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to read invite from Firebase RTDB")
+			}
+		case game.HasPassword:
+			if err := checkLobbyPassword(ctx, dbClient, gameKey, playerData.Password); err != nil {
+				return err
+			}
+		case game.Visibility() == model.VisibilityPrivate:
+			return responses.NewError(fiber.StatusForbidden, "invite_only", "This lobby is invite-only")
+		}
+
+		// Assign the user ID to the player and add them to the game
+		player := model.NewPlayer(playerData.Name)
+		player.Chips = game.Settings.WithDefaults().StartingChips
+		player.UserID = userID
+		game.Players = append(game.Players, player)
+		return nil
 	}
-	if err := c.BodyParser(&playerData); err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid player data")
+
+	// The invite is redeemed by the actor once the player is saved, so a failed join does not use it up
+	var game *model.Game
+	if serr := sendCommand(c.UserContext(), gameKey, func(a *gameActor) {
+		if game, err = a.update(c.UserContext(), dbClient, join); err != nil || claims == nil {
+			return
+		}
+		if rerr := invite.Redeem(c.UserContext(), dbClient, claims, userID); rerr != nil {
+			logging.FromCtx(c).Warn("Failed to redeem invite", logging.KeyGameID, gameKey, logging.KeyError, rerr)
+		}
+	}); serr != nil {
+		return serr
 	}
-	if playerData.Name == "" {
-		return c.Status(fiber.StatusBadRequest).SendString("Player name is empty")
+	if err != nil {
+		return err
 	}
 
-	// Assign the user ID to the player
-	userID := c.Locals("user").(string)
-	player := model.NewPlayer(playerData.Name)
-	player.UserID = userID
+	c.Locals("gameID", gameKey)
+	return c.JSON(game)
+}
 
-	// Add new player to the game
-	game.Players = append(game.Players, player)
+// LeaveGame removes a player from a lobby
+// @Summary Leave a lobby
+// @Description Removes the player from the lobby identified by the provided lobby code before the game starts. Players may remove themselves and the host may remove anyone. When the host leaves the next human player becomes host, and a lobby left without human players is abandoned.
+// @Tags Games
+// @Produce json
+// @Param lobbyCode path string true "Lobby code"
+// @Param playerName path string true "Player name"
+// @Success 200 {object} Game
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /v1/lobbies/{lobbyCode}/players/{playerName} [delete]
+func LeaveGame(c *fiber.Ctx, dbClient *db.Client) error {
+	playerName := c.Params("playerName")
 
-	// Save the game with the new player back to Firebase RTDB
-	if err := gameSnapshot.Set(context.Background(), &game); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save updated game to Firebase RTDB")
+	gameID, err := GetGameIDByLobbyCode(c, dbClient)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := c.Locals("user").(string)
+	game, err := updateGame(c.UserContext(), dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+		player := game.PlayerNamed(playerName)
+		if player == nil {
+			return fmt.Errorf("%w: %s", model.ErrPlayerNotFound, playerName)
+		}
+		if player.UserID != userID && !game.IsHost(userID) {
+			return responses.NewError(fiber.StatusForbidden, "host_only", "Only the host can remove other players")
+		}
+		return game.LeaveLobby(playerName)
+	})
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(game)
@@ -351,7 +486,7 @@ func JoinGame(c *fiber.Ctx, dbClient *db.Client) error {
 
 // takeTurn performs a player's turn in the game
 // @Summary Perform player's turn
-// @Description Takes a turn for the player in the game identified by the provided game ID in the Firebase Realtime Database
+// @Description Takes a turn for the player in the game identified by the provided game ID in the Firebase Realtime Database. The game must have started, players roll their own turns and the host rolls for the bots.
 // @Tags Games
 // @Accept json
 // @Produce json
@@ -361,27 +496,25 @@ func JoinGame(c *fiber.Ctx, dbClient *db.Client) error {
 // @Success 200 {object} Game
 // @Failure 404 {object} ErrorResponse
 // @Failure 403 {object} ErrorResponse
-// @Router /games/{gameID}/turn [post]
+// @Router /v1/games/{gameID}/turns [post]
 func TakeTurn(c *fiber.Ctx, dbClient *db.Client) error {
 	gameID := c.Params("gameID")
-	gameRef := dbClient.NewRef("games/" + gameID)
-
-	// Get the game from the Firebase RTDB
-	game := &model.Game{}
-	if err := gameRef.Get(context.Background(), game); err != nil {
-		return c.Status(fiber.StatusNotFound).SendString("Game not found")
-	}
 
-	if game.GameOver {
-		return c.Status(fiber.StatusForbidden).SendString("Game is over")
+	userID, _ := c.Locals("user").(string)
+	game, err := updateGame(c.UserContext(), dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+		if err := game.CheckTurn(userID); err != nil {
+			return err
+		}
+		playTurn(ctx, gameID, game)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	game.PlayTurn()
-
-	// Update the game in the Firebase RTDB
-	err := gameRef.Set(context.Background(), game)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to save updated game to Firebase RTDB")
+	metrics.TurnPlayed()
+	if game.GameOver && game.Winner != nil {
+		metrics.GameFinished(len(game.Players), game.Winner.IsBot())
 	}
 
 	return c.JSON(fiber.Map{
@@ -389,26 +522,99 @@ func TakeTurn(c *fiber.Ctx, dbClient *db.Client) error {
 	})
 }
 
+// playTurn plays the turn of the current player inside an engine span recording what the turn did
+func playTurn(ctx context.Context, gameID string, game *model.Game) {
+	_, span := tracing.Tracer().Start(ctx, "lcr.PlayTurn", trace.WithAttributes(
+		attribute.String("game.id", gameID),
+		attribute.Int("game.turn", game.Turn),
+		attribute.Int("game.players", len(game.Players)),
+	))
+	defer span.End()
+
+	game.PlayTurn()
+
+	span.SetAttributes(
+		attribute.Int("game.pot", game.Pot),
+		attribute.Bool("game.over", game.GameOver),
+	)
+}
+
 // getGame retrieves the game by game ID
 // @Summary Get game by ID
-// @Description Retrieves the game based on the provided game ID from the Firebase Realtime Database
+// @Description Retrieves the game based on the provided game ID from the Firebase Realtime Database. The ETag is the version of the game, so If-None-Match answers 304 while it has not changed. With waitForVersion the request waits until the game reaches that version, or until the timeout, then answers with the game as it is.
 // @Tags Games
 // @Accept json
 // @Produce json
 // @Param c path string true "Fiber context"
 // @Param dbClient path string true "Database client"
 // @Param gameID path string true "Game ID"
+// @Param waitForVersion query int false "Wait until the game has at least this version, usually the Version the client has plus one"
+// @Param timeout query string false "How long to wait for the version, e.g. 30s (default 30s, at most 60s)"
+// @Param If-None-Match header string false "ETag of the version the client has"
 // @Success 200 {object} Game
+// @Success 304
 // @Failure 404 {object} ErrorResponse
-// @Router /games/{gameID} [get]
+// @Failure 503 {object} ErrorResponse
+// @Router /v1/games/{gameID} [get]
 func GetGame(c *fiber.Ctx, dbClient *db.Client) error {
 	gameID := c.Params("gameID")
-	gameRef := dbClient.NewRef("games/" + gameID)
 
-	// Get the game from the Firebase RTDB
-	game := &model.Game{}
-	if err := gameRef.Get(context.Background(), game); err != nil {
-		return c.Status(fiber.StatusNotFound).SendString("Game not found")
+	// Get the game from the Firebase RTDB, once it reaches the awaited version for a long poll
+	var game *model.Game
+	var err error
+	if c.Query("waitForVersion") != "" {
+		timeout, terr := waitTimeout(c)
+		if terr != nil {
+			return terr
+		}
+		game, err = waitForVersion(c.UserContext(), dbClient, gameID, int64(c.QueryInt("waitForVersion")), timeout)
+	} else {
+		game, err = loadGame(c.UserContext(), dbClient, gameID)
+	}
+	if err != nil {
+		return err
+	}
+
+	etag := gameETag(game)
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.JSON(fiber.Map{
+		"game": game,
+	})
+}
+
+// startGame starts the game once every player is ready
+// @Summary Start a game
+// @Description Starts the game identified by the provided lobby code and removes it from the lobby browser. Every player must be ready.
+// @Tags Games
+// @Accept json
+// @Produce json
+// @Param lobbyCode path string true "Lobby code"
+// @Success 200 {object} Game
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /v1/lobbies/{lobbyCode}/start [post]
+func StartGame(c *fiber.Ctx, dbClient *db.Client) error {
+	gameID, err := GetGameIDByLobbyCode(c, dbClient)
+	if err != nil {
+		return err
+	}
+
+	game, err := updateGame(c.UserContext(), dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+		_, span := tracing.Tracer().Start(ctx, "lcr.Start", trace.WithAttributes(
+			attribute.String("game.id", gameID),
+			attribute.Int("game.players", len(game.Players)),
+		))
+		defer span.End()
+		return game.Start()
+	})
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(fiber.Map{
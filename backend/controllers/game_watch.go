@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/model"
+	"backend/responses"
+	"backend/validate"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Bounds of the timeout of a long-polling GetGame
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 60 * time.Second
+)
+
+// waitRecheckInterval is how often the games with long polls are re-read, to see the changes made by
+// other server instances or the janitor, which do not notify the waiters of this one
+const waitRecheckInterval = 2 * time.Second
+
+// gameWatch is shared by the long polls waiting on one game. It holds the latest state of the game
+// seen by this server, and a channel closed when a newer one arrives.
+type gameWatch struct {
+	waiters int
+	game    *model.Game
+	changed chan struct{}
+	// stop ends the recheck of the game once the last waiter has left
+	stop chan struct{}
+}
+
+// gameWatchers holds the watch of each game that has waiters, keyed by game ID
+var gameWatchers = struct {
+	sync.Mutex
+	watches map[string]*gameWatch
+}{watches: map[string]*gameWatch{}}
+
+// watchGame adds a waiter to the watch of the game, starting the watch and its recheck for the first
+// waiter. Waiters call unwatchGame when they leave.
+func watchGame(dbClient *db.Client, gameID string) *gameWatch {
+	gameWatchers.Lock()
+	defer gameWatchers.Unlock()
+	w, ok := gameWatchers.watches[gameID]
+	if !ok {
+		w = &gameWatch{changed: make(chan struct{}), stop: make(chan struct{})}
+		gameWatchers.watches[gameID] = w
+		go w.recheck(dbClient, gameID)
+	}
+	w.waiters++
+	return w
+}
+
+// unwatchGame removes a waiter from the watch of the game, dropping the watch with its last waiter
+func unwatchGame(gameID string, w *gameWatch) {
+	gameWatchers.Lock()
+	defer gameWatchers.Unlock()
+	w.waiters--
+	if w.waiters == 0 {
+		close(w.stop)
+		delete(gameWatchers.watches, gameID)
+	}
+}
+
+// latest returns the latest state of the game seen by the watch, nil if none yet, and a channel closed
+// when a newer one arrives
+func (w *gameWatch) latest() (*model.Game, <-chan struct{}) {
+	gameWatchers.Lock()
+	defer gameWatchers.Unlock()
+	return w.game, w.changed
+}
+
+// publish hands a state of the game to the waiters if it is newer than the one they have. It must be
+// called with gameWatchers locked.
+func (w *gameWatch) publish(game *model.Game) {
+	if w.game != nil && game.Version <= w.game.Version {
+		return
+	}
+	w.game = game
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+// recheck re-reads the game every waitRecheckInterval until the watch stops, one read shared by
+// all the waiters
+func (w *gameWatch) recheck(dbClient *db.Client, gameID string) {
+	ticker := time.NewTicker(waitRecheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), waitRecheckInterval)
+			game, err := loadGame(ctx, dbClient, gameID)
+			cancel()
+			if err != nil {
+				continue
+			}
+			gameWatchers.Lock()
+			w.publish(game)
+			gameWatchers.Unlock()
+		}
+	}
+}
+
+// notifyGameChanged hands a game that has just been saved to the long polls waiting on it
+func notifyGameChanged(game *model.Game) {
+	gameWatchers.Lock()
+	defer gameWatchers.Unlock()
+	if w, ok := gameWatchers.watches[game.GameID]; ok {
+		w.publish(game)
+	}
+}
+
+// gameETag is the entity tag of a version of a game
+func gameETag(game *model.Game) string {
+	return `"` + strconv.FormatInt(game.Version, 10) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// waitTimeout parses the timeout query param of a long poll, a Go duration such as 30s
+func waitTimeout(c *fiber.Ctx) (time.Duration, error) {
+	value := c.Query("timeout")
+	if value == "" {
+		return defaultWaitTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 || timeout > maxWaitTimeout {
+		return 0, validate.Failed([]responses.FieldError{{
+			In: validate.InQuery, Field: "timeout", Rule: "duration",
+			Message: "must be a duration between 0s and " + maxWaitTimeout.String() + ", e.g. 30s",
+		}})
+	}
+	return timeout, nil
+}
+
+// waitForVersion returns the game once its version is at least version, or as it is when timeout
+// passes. A shutdown ends the wait with 503, so the client polls again on another instance.
+func waitForVersion(ctx context.Context, dbClient *db.Client, gameID string, version int64, timeout time.Duration) (*model.Game, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	// Watch before reading, so a change saved in between is not missed
+	w := watchGame(dbClient, gameID)
+	defer unwatchGame(gameID, w)
+	game, err := loadGame(ctx, dbClient, gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		latest, changed := w.latest()
+		if latest != nil && latest.Version > game.Version {
+			game = latest
+		}
+		if game.Version >= version {
+			return game, nil
+		}
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return game, nil
+		case <-ShuttingDown():
+			return nil, responses.NewError(fiber.StatusServiceUnavailable, "unavailable", ShutdownReason)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
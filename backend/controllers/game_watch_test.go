@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/model"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newGameApp returns an app serving GetGame
+func newGameApp(dbClient *db.Client) *fiber.App {
+	app := newTestApp("user-ann")
+	app.Get("/games/:gameID", handle(dbClient, GetGame))
+	return app
+}
+
+// getGame makes a GetGame request and returns the status and the version of the game in the response
+func getGame(t *testing.T, app *fiber.App, target, ifNoneMatch string) (int, int64) {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodGet, target, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+	}
+	status, body := send(t, app, req)
+	if status != fiber.StatusOK {
+		return status, 0
+	}
+	var resp struct {
+		Game *model.Game `json:"game"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return status, resp.Game.Version
+}
+
+// waitForWatchers waits until the game has the given number of long polls waiting on it
+func waitForWatchers(t *testing.T, gameID string, waiters int) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+		gameWatchers.Lock()
+		w, ok := gameWatchers.watches[gameID]
+		got := 0
+		if ok {
+			got = w.waiters
+		}
+		gameWatchers.Unlock()
+		if got == waiters {
+			return
+		}
+	}
+	t.Fatalf("game %s never had %d waiters", gameID, waiters)
+}
+
+func TestGetGameETag(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedGame(t, fake, "watch-etag")
+	app := newGameApp(dbClient)
+
+	tests := []struct {
+		name        string
+		target      string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{name: "no etag", target: "/games/watch-etag", wantStatus: fiber.StatusOK},
+		{name: "current etag", target: "/games/watch-etag", ifNoneMatch: `"1"`, wantStatus: fiber.StatusNotModified},
+		{name: "weak current etag in a list", target: "/games/watch-etag", ifNoneMatch: `"0", W/"1"`, wantStatus: fiber.StatusNotModified},
+		{name: "old etag", target: "/games/watch-etag", ifNoneMatch: `"0"`, wantStatus: fiber.StatusOK},
+		{name: "missing game", target: "/games/watch-missing", wantStatus: fiber.StatusNotFound},
+		{name: "bad timeout", target: "/games/watch-etag?waitForVersion=2&timeout=2h", wantStatus: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := getGame(t, app, tt.target, tt.ifNoneMatch); status != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGetGameLongPoll(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	seedGame(t, fake, "watch-poll")
+	app := newGameApp(dbClient)
+
+	// A version the game already has is answered at once
+	if status, version := getGame(t, app, "/games/watch-poll?waitForVersion=1", ""); status != fiber.StatusOK || version != 1 {
+		t.Fatalf("got status %d version %d, want the game at version 1", status, version)
+	}
+
+	// A version the game does not reach is answered with the game as it is once the timeout passes
+	start := time.Now()
+	if status, version := getGame(t, app, "/games/watch-poll?waitForVersion=5&timeout=50ms", ""); status != fiber.StatusOK || version != 1 {
+		t.Fatalf("got status %d version %d, want the game at version 1", status, version)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("answered after %s, before the timeout", elapsed)
+	}
+
+	// An update saved on this server wakes the poll without waiting for a recheck
+	done := make(chan int64)
+	go func() {
+		_, version := getGame(t, app, "/games/watch-poll?waitForVersion=2&timeout=10s", `"1"`)
+		done <- version
+	}()
+	waitForWatchers(t, "watch-poll", 1)
+	start = time.Now()
+	if _, err := updateGame(context.Background(), dbClient, "watch-poll", func(ctx context.Context, game *model.Game) error {
+		game.Pot++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if version := <-done; version != 2 {
+		t.Fatalf("got version %d, want 2", version)
+	}
+	if elapsed := time.Since(start); elapsed >= waitRecheckInterval {
+		t.Fatalf("answered after %s, not woken by the update", elapsed)
+	}
+
+	waitForWatchers(t, "watch-poll", 0)
+	gameWatchers.Lock()
+	_, ok := gameWatchers.watches["watch-poll"]
+	gameWatchers.Unlock()
+	if ok {
+		t.Fatal("watch kept after its last waiter left")
+	}
+}
+
+func TestGetGameLongPollSharesRechecks(t *testing.T) {
+	dbClient, fake := newTestDB(t)
+	game := seedGame(t, fake, "watch-shared")
+	app := newGameApp(dbClient)
+
+	const waiters = 3
+	var wg sync.WaitGroup
+	versions := make(chan int64, waiters)
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, version := getGame(t, app, "/games/watch-shared?waitForVersion=2&timeout=10s", "")
+			versions <- version
+		}()
+	}
+	waitForWatchers(t, "watch-shared", waiters)
+	for fake.readsOf("games/watch-shared") < waiters {
+		time.Sleep(time.Millisecond)
+	}
+	before := fake.readsOf("games/watch-shared")
+
+	// A change made by another server is only seen by the recheck
+	game.Version = 2
+	fake.set(t, "games/watch-shared", game)
+	wg.Wait()
+	close(versions)
+	for version := range versions {
+		if version != 2 {
+			t.Fatalf("got version %d, want 2", version)
+		}
+	}
+	if reads := fake.readsOf("games/watch-shared") - before; reads != 1 {
+		t.Fatalf("the waiters made %d rechecks, want one shared", reads)
+	}
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"backend/logging"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReadinessCheck is a dependency that must be reachable for the server to be ready
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthResponse represents the response structure for the liveness endpoint
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// ReadinessResponse represents the response structure for the readiness endpoint
+type ReadinessResponse struct {
+	Status string `json:"status"`
+	// Checks maps each dependency to "ok", "failed" or "timeout". The reason of a failure is only logged.
+	Checks map[string]string `json:"checks"`
+}
+
+// VersionResponse represents the response structure for the version endpoint
+type VersionResponse struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	GoVersion  string `json:"goVersion"`
+}
+
+// Healthz reports that the process is alive
+// @Summary Liveness probe
+// @Description Reports that the server process is running. It does not check any dependency.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func Healthz(c *fiber.Ctx) error {
+	return c.JSON(HealthResponse{Status: "ok"})
+}
+
+// Readyz runs every check concurrently, each bounded by timeout, and reports whether all of them passed
+// @Summary Readiness probe
+// @Description Checks that the game store and the auth provider are reachable. Responds with 503 if any of them is not.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /readyz [get]
+func Readyz(c *fiber.Ctx, checks []ReadinessCheck, timeout time.Duration) error {
+	res := ReadinessResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check ReadinessCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			result := "ok"
+			if err := check.Check(ctx); err != nil {
+				result = "failed"
+				if ctx.Err() != nil {
+					result = "timeout"
+				}
+				slog.Warn("Readiness check failed", "check", check.Name, "result", result, logging.KeyError, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.Checks[check.Name] = result
+			if result != "ok" {
+				res.Status = "unavailable"
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	if res.Status != "ok" {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(res)
+}
+
+// Version reports the version the server was built from
+// @Summary Build version
+// @Description Returns the module version, VCS revision and Go version of the running server.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} VersionResponse
+// @Router /version [get]
+func Version(c *fiber.Ctx) error {
+	return c.JSON(buildVersion())
+}
+
+var (
+	versionOnce sync.Once
+	version     VersionResponse
+)
+
+// buildVersion reads the build info embedded by the Go toolchain once
+func buildVersion() VersionResponse {
+	versionOnce.Do(func() {
+		version = VersionResponse{Version: "(devel)"}
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		version.GoVersion = info.GoVersion
+		if info.Main.Version != "" {
+			version.Version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				version.Commit = setting.Value
+			case "vcs.time":
+				version.CommitTime = setting.Value
+			case "vcs.modified":
+				version.Modified = setting.Value == "true"
+			}
+		}
+	})
+	return version
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+	"github.com/skip2/go-qrcode"
+
+	"backend/invite"
+	"backend/logging"
+	"backend/model"
+	"backend/responses"
+	"backend/validate"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Invites signs and verifies invite tokens. It is set up by InitInvites.
+var Invites *invite.Signer
+
+// inviteBaseURL is the frontend URL the invite token is appended to
+var inviteBaseURL string
+
+const defaultInviteTTL = 24 * time.Hour
+
+// InitInvites sets up invite signing. baseURL is the frontend join page, e.g. https://lcr.up.railway.app/join/
+func InitInvites(signer *invite.Signer, baseURL string) {
+	Invites = signer
+	inviteBaseURL = baseURL
+}
+
+// CreateInviteRequest represents the request body for the create invite endpoint
+type CreateInviteRequest struct {
+	// ExpiresIn is the lifetime of the invite in seconds, 24 hours by default and at most 7 days
+	ExpiresIn int  `json:"ExpiresIn" validate:"min=0,max=604800"`
+	SingleUse bool `json:"SingleUse"`
+}
+
+// CreateInviteResponse represents the response structure for the create invite endpoint
+type CreateInviteResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	SingleUse bool      `json:"singleUse"`
+}
+
+// loadHostedLobby finds the lobby identified by the lobby code and checks that the current user is its host
+func loadHostedLobby(c *fiber.Ctx, dbClient *db.Client) (string, *model.Game, error) {
+	gameID, err := GetGameIDByLobbyCode(c, dbClient)
+	if err != nil {
+		return "", nil, err
+	}
+
+	game, err := loadGame(c.UserContext(), dbClient, gameID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	userID, _ := c.Locals("user").(string)
+	if game.Creator == nil || game.Creator.UserID != userID {
+		return "", nil, responses.NewError(fiber.StatusForbidden, "host_only", "Only the host can invite players")
+	}
+	if !game.IsOpenLobby() {
+		return "", nil, model.ErrGameStarted
+	}
+	return gameID, game, nil
+}
+
+// CreateInvite creates a signed invite to a lobby
+// @Summary Create an invite
+// @Description Creates a signed invite token for the lobby identified by the provided lobby code. The token can be used in place of the lobby code to join, skipping the lobby password. Only the host can create invites.
+// @Tags Games
+// @Accept json
+// @Produce json
+// @Param lobbyCode path string true "Lobby code"
+// @Param invite body CreateInviteRequest false "Invite options"
+// @Success 200 {object} CreateInviteResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /v1/lobbies/{lobbyCode}/invites [post]
+func CreateInvite(c *fiber.Ctx, dbClient *db.Client) error {
+	req := &CreateInviteRequest{}
+	if len(c.Body()) > 0 {
+		if err := validate.Body(c, req); err != nil {
+			return err
+		}
+	}
+
+	ttl := defaultInviteTTL
+	if req.ExpiresIn != 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+
+	gameID, game, err := loadHostedLobby(c, dbClient)
+	if err != nil {
+		return err
+	}
+
+	token, claims, err := Invites.Create(gameID, game.LobbyCode, ttl, req.SingleUse)
+	if err != nil {
+		logging.FromCtx(c).Error("Failed to create invite", logging.KeyGameID, gameID, logging.KeyError, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create invite")
+	}
+
+	return c.JSON(CreateInviteResponse{
+		Token:     token,
+		URL:       inviteBaseURL + token,
+		ExpiresAt: claims.Expiry(),
+		SingleUse: claims.SingleUse,
+	})
+}
+
+// InviteQRCode renders the join URL of an invite as a QR code
+// @Summary Get invite QR code
+// @Description Renders a PNG QR code of the join URL for the lobby identified by the provided lobby code. The invite given in the token query parameter is used, otherwise a reusable invite valid for 24 hours is created. Only the host can get it.
+// @Tags Games
+// @Produce png
+// @Param lobbyCode path string true "Lobby code"
+// @Param token query string false "Invite token"
+// @Param size query int false "Image size in pixels, between 128 and 1024 (default 256)"
+// @Success 200 {file} file
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /v1/lobbies/{lobbyCode}/invites/qr.png [get]
+func InviteQRCode(c *fiber.Ctx, dbClient *db.Client) error {
+	size := c.QueryInt("size", 256)
+
+	gameID, game, err := loadHostedLobby(c, dbClient)
+	if err != nil {
+		return err
+	}
+
+	token := c.Query("token")
+	if token != "" {
+		claims, err := Invites.Verify(token)
+		if err != nil {
+			return err
+		}
+		if claims.GameID != gameID {
+			return fmt.Errorf("%w: invite is for another game", invite.ErrInvalid)
+		}
+	} else {
+		if token, _, err = Invites.Create(gameID, game.LobbyCode, defaultInviteTTL, false); err != nil {
+			logging.FromCtx(c).Error("Failed to create invite", logging.KeyGameID, gameID, logging.KeyError, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to create invite")
+		}
+	}
+
+	png, err := qrcode.Encode(inviteBaseURL+token, qrcode.Medium, size)
+	if err != nil {
+		logging.FromCtx(c).Error("Failed to render invite QR code", logging.KeyGameID, gameID, logging.KeyError, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render QR code")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Type("png")
+	return c.Send(png)
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+
+	"firebase.google.com/go/v4/db"
+
+	"backend/lobbycode"
+	"backend/logging"
+	"backend/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LobbyCodes hands out lobby codes. It is set up by InitLobbyCodes.
+var LobbyCodes *lobbycode.Service
+
+// InitLobbyCodes sets up the lobby code service. Codes held by games that are over or gone are recycled.
+func InitLobbyCodes(dbClient *db.Client, cfg lobbycode.Config) error {
+	service, err := lobbycode.NewService(dbClient, cfg, func(ctx context.Context, gameID string) (bool, error) {
+		var game *model.Game
+		if err := dbClient.NewRef("games/"+gameID).Get(ctx, &game); err != nil {
+			return false, err
+		}
+		return game != nil && !game.GameOver, nil
+	})
+	if err != nil {
+		return err
+	}
+	LobbyCodes = service
+	return nil
+}
+
+// ReleaseLobbyCode hands the lobby code of a finished game back to the pool
+func ReleaseLobbyCode(ctx context.Context, gameID string, game *model.Game) {
+	if game == nil || !game.GameOver {
+		return
+	}
+	if err := LobbyCodes.Release(ctx, game.LobbyCode, gameID); err != nil {
+		slog.ErrorContext(ctx, "Failed to release lobby code", "lobbyCode", game.LobbyCode, logging.KeyGameID, gameID, logging.KeyError, err)
+	}
+}
+
+// gameIDByLobbyCode returns the ID of the game holding the lobby code. Its reservation is the source of
+// truth, since finished games keep their code after it is handed out again; codes handed out before
+// reservations existed are looked up among the unfinished games.
+func gameIDByLobbyCode(ctx context.Context, dbClient *db.Client, lobbyCode string) (string, error) {
+	gameID, err := LobbyCodes.Holder(ctx, lobbyCode)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to look up lobby code in Firebase RTDB")
+	}
+	if gameID != "" {
+		return gameID, nil
+	}
+
+	query := dbClient.NewRef("games").OrderByChild("LobbyCode").EqualTo(lobbyCode)
+	results, err := query.GetOrdered(ctx)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to query games from Firebase RTDB")
+	}
+	for _, result := range results {
+		var game model.Game
+		if err := result.Unmarshal(&game); err == nil && !game.GameOver {
+			return result.Key(), nil
+		}
+	}
+	return "", model.ErrGameNotFound
+}
+
+// lobbyCodeParam returns the lobbyCode path parameter in its canonical form
+func lobbyCodeParam(c *fiber.Ctx) string {
+	return lobbycode.Normalize(c.Params("lobbyCode"))
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"firebase.google.com/go/v4/db"
+
+	"backend/model"
+)
+
+// lobbyIndexPath is the RTDB node holding one LobbySummary per listed open lobby, keyed by game ID.
+// The node needs an ".indexOn": "CreatedAtMs" rule.
+const lobbyIndexPath = "lobbies"
+
+// UpdateLobbyIndex adds, refreshes or removes the index entry of a game depending on whether it is still a listed open lobby
+func UpdateLobbyIndex(ctx context.Context, dbClient *db.Client, gameID string, game *model.Game) error {
+	ref := dbClient.NewRef(lobbyIndexPath + "/" + gameID)
+	if game == nil || !game.IsBrowsable() {
+		return ref.Delete(ctx)
+	}
+	return ref.Set(ctx, game.LobbySummary(gameID))
+}
+
+// ReconcileLobbyIndex makes the lobby index match the given games, removing entries of games that are gone,
+// no longer open or no longer listed, and adding any listed open lobby that is missing
+func ReconcileLobbyIndex(ctx context.Context, dbClient *db.Client, games map[string]*model.Game) (int, error) {
+	indexRef := dbClient.NewRef(lobbyIndexPath)
+	var index map[string]*model.LobbySummary
+	if err := indexRef.Get(ctx, &index); err != nil {
+		return 0, err
+	}
+
+	fixed := 0
+	for gameID := range index {
+		if game, ok := games[gameID]; !ok || game == nil || !game.IsBrowsable() {
+			if err := indexRef.Child(gameID).Delete(ctx); err != nil {
+				return fixed, err
+			}
+			fixed++
+		}
+	}
+	for gameID, game := range games {
+		if game == nil || !game.IsBrowsable() {
+			continue
+		}
+		if _, ok := index[gameID]; ok {
+			continue
+		}
+		if err := indexRef.Child(gameID).Set(ctx, game.LobbySummary(gameID)); err != nil {
+			return fixed, err
+		}
+		fixed++
+	}
+	return fixed, nil
+}
+
+// LobbyFilter selects which lobbies are returned by the lobby browser
+type LobbyFilter struct {
+	// HasPassword, when set, only keeps the lobbies with or without a password
+	HasPassword *bool
+	HasSeats    bool
+	RuleSet     string
+	MinPlayers  int
+	MaxPlayers  int
+}
+
+// Matches reports whether a lobby passes the filter
+func (f *LobbyFilter) Matches(lobby *model.LobbySummary) bool {
+	if f.HasPassword != nil && lobby.HasPassword != *f.HasPassword {
+		return false
+	}
+	if f.HasSeats && lobby.FreeSeats() == 0 {
+		return false
+	}
+	if f.RuleSet != "" && lobby.RuleSet != f.RuleSet {
+		return false
+	}
+	if f.MinPlayers > 0 && lobby.Players < f.MinPlayers {
+		return false
+	}
+	if f.MaxPlayers > 0 && lobby.Players > f.MaxPlayers {
+		return false
+	}
+	return true
+}
+
+// lobbyCursor marks the position of the last lobby returned in a page
+type lobbyCursor struct {
+	createdAtMs int64
+	gameID      string
+}
+
+func (c *lobbyCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d|%s", c.createdAtMs, c.gameID)))
+}
+
+func decodeLobbyCursor(value string) (*lobbyCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	createdAtMs, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &lobbyCursor{createdAtMs: createdAtMs, gameID: parts[1]}, nil
+}
+
+// isPast reports whether the lobby comes after the cursor in the given sort order
+func (c *lobbyCursor) isPast(lobby *model.LobbySummary, newestFirst bool) bool {
+	if c == nil {
+		return true
+	}
+	if lobby.CreatedAtMs != c.createdAtMs {
+		return (lobby.CreatedAtMs > c.createdAtMs) != newestFirst
+	}
+	if lobby.GameID == c.gameID {
+		return false
+	}
+	return (lobby.GameID > c.gameID) != newestFirst
+}
+
+// maxLobbyBatches bounds the number of index reads a single page may need when most lobbies are filtered out
+const maxLobbyBatches = 10
+
+// queryLobbies reads one page of lobbies from the index in creation order, starting after the cursor.
+// It returns the page and the cursor of the next page, which is empty when there are no more lobbies.
+func queryLobbies(ctx context.Context, dbClient *db.Client, filter *LobbyFilter, cursor *lobbyCursor, limit int, newestFirst bool) ([]*model.LobbySummary, string, error) {
+	page := []*model.LobbySummary{}
+	batchSize := limit + 1
+
+	for batch := 0; batch < maxLobbyBatches; batch++ {
+		query := dbClient.NewRef(lobbyIndexPath).OrderByChild("CreatedAtMs")
+		if newestFirst {
+			if cursor != nil {
+				query = query.EndAt(cursor.createdAtMs)
+			}
+			query = query.LimitToLast(batchSize)
+		} else {
+			if cursor != nil {
+				query = query.StartAt(cursor.createdAtMs)
+			}
+			query = query.LimitToFirst(batchSize)
+		}
+
+		results, err := query.GetOrdered(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if newestFirst {
+			for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+				results[i], results[j] = results[j], results[i]
+			}
+		}
+
+		advanced := false
+		for _, result := range results {
+			lobby := &model.LobbySummary{}
+			if err := result.Unmarshal(lobby); err != nil {
+				return nil, "", err
+			}
+			lobby.GameID = result.Key()
+			if !cursor.isPast(lobby, newestFirst) {
+				continue
+			}
+
+			cursor = &lobbyCursor{createdAtMs: lobby.CreatedAtMs, gameID: lobby.GameID}
+			advanced = true
+			if !filter.Matches(lobby) {
+				continue
+			}
+			page = append(page, lobby)
+			if len(page) == limit {
+				return page, cursor.encode(), nil
+			}
+		}
+
+		if len(results) < batchSize {
+			return page, "", nil
+		}
+		if !advanced {
+			// A whole batch shared the cursor's timestamp, read a bigger one
+			batchSize *= 2
+		}
+	}
+
+	// Give the caller what we have and let them continue from where we stopped
+	if cursor == nil {
+		return page, "", nil
+	}
+	return page, cursor.encode(), nil
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"backend/model"
+)
+
+// seedLobbies stores the index entry of each lobby
+func seedLobbies(t *testing.T, fake *fakeRTDB, lobbies []*model.LobbySummary) {
+	t.Helper()
+	for _, lobby := range lobbies {
+		fake.set(t, lobbyIndexPath+"/"+lobby.GameID, lobby)
+	}
+}
+
+func TestQueryLobbies(t *testing.T) {
+	lobby := func(ms int64, id string, players int, hasPassword bool) *model.LobbySummary {
+		return &model.LobbySummary{GameID: id, LobbyCode: strings.ToUpper(id), Players: players, MaxPlayers: 4, RuleSet: "classic", CreatedAtMs: ms, HasPassword: hasPassword}
+	}
+	lobbies := []*model.LobbySummary{
+		lobby(1000, "a", 1, false),
+		lobby(2000, "b", 4, false),
+		lobby(2000, "c", 2, true),
+		lobby(2000, "d", 3, false),
+		lobby(3000, "e", 1, true),
+		lobby(4000, "f", 2, false),
+		lobby(5000, "g", 4, false),
+	}
+	noPassword := false
+
+	tests := []struct {
+		name        string
+		filter      *LobbyFilter
+		limit       int
+		newestFirst bool
+		want        string
+	}{
+		{name: "newest first", filter: &LobbyFilter{}, limit: 2, newestFirst: true, want: "g f | e d | c b | a"},
+		{name: "oldest first", filter: &LobbyFilter{}, limit: 3, newestFirst: false, want: "a b c | d e f | g"},
+		{name: "one per page through a shared timestamp", filter: &LobbyFilter{}, limit: 1, newestFirst: false, want: "a | b | c | d | e | f | g"},
+		{name: "whole index in one page", filter: &LobbyFilter{}, limit: 20, newestFirst: true, want: "g f e d c b a"},
+		{name: "without a password", filter: &LobbyFilter{HasPassword: &noPassword}, limit: 2, newestFirst: true, want: "g f | d b | a"},
+		{name: "with free seats", filter: &LobbyFilter{HasSeats: true}, limit: 2, newestFirst: false, want: "a c | d e | f"},
+		{name: "player range", filter: &LobbyFilter{MinPlayers: 2, MaxPlayers: 3}, limit: 5, newestFirst: true, want: "f d c"},
+		{name: "nothing matches", filter: &LobbyFilter{RuleSet: "wild"}, limit: 2, newestFirst: true, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbClient, fake := newTestDB(t)
+			seedLobbies(t, fake, lobbies)
+
+			var pages []string
+			var cursor *lobbyCursor
+			for i := 0; ; i++ {
+				if i > len(lobbies) {
+					t.Fatalf("still paging after %d pages: %v", i, pages)
+				}
+				page, next, err := queryLobbies(context.Background(), dbClient, tt.filter, cursor, tt.limit, tt.newestFirst)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var ids []string
+				for _, lobby := range page {
+					ids = append(ids, lobby.GameID)
+				}
+				if len(ids) > 0 {
+					pages = append(pages, strings.Join(ids, " "))
+				}
+				if next == "" {
+					break
+				}
+				if cursor, err = decodeLobbyCursor(next); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := strings.Join(pages, " | "); got != tt.want {
+				t.Fatalf("got pages %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLobbyCursor(t *testing.T) {
+	cursor := &lobbyCursor{createdAtMs: 1700000000000, gameID: "-Nabc|def"}
+	got, err := decodeLobbyCursor(cursor.encode())
+	if err != nil || *got != *cursor {
+		t.Fatalf("got %+v, %v, want %+v", got, err, cursor)
+	}
+
+	for _, value := range []string{"", "not base64!", "bm8gc2VwYXJhdG9y", base64.RawURLEncoding.EncodeToString([]byte("soon|game1"))} {
+		if _, err := decodeLobbyCursor(value); err == nil {
+			t.Errorf("decoded invalid cursor %q", value)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/db"
+	"golang.org/x/crypto/bcrypt"
+
+	"backend/responses"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// lobbyPasswordsPath is the RTDB node holding the bcrypt hash of each lobby password, keyed by game ID.
+// It must not be readable by clients.
+const lobbyPasswordsPath = "lobbyPasswords"
+
+// setLobbyPassword stores the hash of the lobby password of a game
+func setLobbyPassword(ctx context.Context, dbClient *db.Client, gameID, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return dbClient.NewRef(lobbyPasswordsPath+"/"+gameID).Set(ctx, string(hash))
+}
+
+// checkLobbyPassword returns a 403 error unless password matches the lobby password of the game
+func checkLobbyPassword(ctx context.Context, dbClient *db.Client, gameID, password string) error {
+	var hash string
+	if err := dbClient.NewRef(lobbyPasswordsPath+"/"+gameID).Get(ctx, &hash); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve lobby password from Firebase RTDB")
+	}
+	if password == "" {
+		return responses.NewError(fiber.StatusForbidden, "password_required", "This lobby requires a password")
+	}
+	if hash == "" || bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return responses.NewError(fiber.StatusForbidden, "wrong_password", "Incorrect lobby password")
+	}
+	return nil
+}
+
+// DeleteLobbyPassword removes the lobby password of a game
+func DeleteLobbyPassword(ctx context.Context, dbClient *db.Client, gameID string) error {
+	return dbClient.NewRef(lobbyPasswordsPath + "/" + gameID).Delete(ctx)
+}
+
+// LobbyPasswordHash returns the stored hash of the lobby password of a game, empty if it has none
+func LobbyPasswordHash(ctx context.Context, dbClient *db.Client, gameID string) (string, error) {
+	var hash string
+	err := dbClient.NewRef(lobbyPasswordsPath+"/"+gameID).Get(ctx, &hash)
+	return hash, err
+}
+
+// SetLobbyPasswordHash stores an already hashed lobby password, e.g. one read by LobbyPasswordHash
+func SetLobbyPasswordHash(ctx context.Context, dbClient *db.Client, gameID, hash string) error {
+	return dbClient.NewRef(lobbyPasswordsPath+"/"+gameID).Set(ctx, hash)
+}
@@ -1,24 +1,75 @@
 package controllers
 
 import (
+	"errors"
+
+	"backend/invite"
+	"backend/lobbycode"
+	"backend/logging"
+	"backend/model"
 	"backend/responses"
+	"backend/tracing"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// ErrorHandler represents a middleware for error handling.
-func ErrorHandler() fiber.ErrorHandler {
-	return func(c *fiber.Ctx, err error) error {
-		// Create a custom response
-		code := fiber.StatusInternalServerError
-		if e, ok := err.(*fiber.Error); ok {
-			code = e.Code
+// domainErrors maps the errors of the game, invite and lobby code packages to their HTTP status and code
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{model.ErrGameNotFound, fiber.StatusNotFound, "game_not_found"},
+	{model.ErrPlayerNotFound, fiber.StatusNotFound, "player_not_found"},
+	{model.ErrGameOver, fiber.StatusConflict, "game_over"},
+	{model.ErrGameNotStarted, fiber.StatusConflict, "game_not_started"},
+	{model.ErrGameStarted, fiber.StatusConflict, "game_started"},
+	{model.ErrNotEnoughPlayers, fiber.StatusConflict, "not_enough_players"},
+	{model.ErrPlayersNotReady, fiber.StatusConflict, "players_not_ready"},
+	{model.ErrLobbyFull, fiber.StatusConflict, "lobby_full"},
+	{model.ErrAlreadyInGame, fiber.StatusConflict, "already_in_game"},
+	{model.ErrNameTaken, fiber.StatusConflict, "name_taken"},
+	{model.ErrNotYourTurn, fiber.StatusForbidden, "not_your_turn"},
+	{model.ErrNotInGame, fiber.StatusForbidden, "not_in_game"},
+	{model.ErrInvalidSettings, fiber.StatusBadRequest, "invalid_settings"},
+	{model.ErrInvalidTurn, fiber.StatusBadRequest, "invalid_turn"},
+	{invite.ErrInvalid, fiber.StatusForbidden, "invite_invalid"},
+	{invite.ErrExpired, fiber.StatusForbidden, "invite_expired"},
+	{invite.ErrUsed, fiber.StatusForbidden, "invite_used"},
+	{lobbycode.ErrExhausted, fiber.StatusServiceUnavailable, "lobby_codes_exhausted"},
+}
+
+// toAPIError turns any error returned by a handler into an error with a status and code
+func toAPIError(err error) *responses.Error {
+	var apiErr *responses.Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for _, d := range domainErrors {
+		if errors.Is(err, d.err) {
+			return responses.NewError(d.status, d.code, err.Error())
 		}
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return responses.NewError(fiberErr.Code, responses.CodeForStatus(fiberErr.Code), fiberErr.Message)
+	}
+	return responses.NewError(fiber.StatusInternalServerError, responses.CodeInternal, "An unexpected error occurred")
+}
 
-		return c.Status(code).JSON(&responses.ErrorResponse{
-			Code:     code,
-			Message:  err.Error(),
-			Internal: "",
+// ErrorHandler writes every error returned by a handler as a responses.ErrorResponse
+func ErrorHandler() fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		apiErr := toAPIError(err)
+		requestID, _ := c.Locals(logging.KeyRequestID).(string)
+		return c.Status(apiErr.Status).JSON(&responses.ErrorResponse{
+			Code:      apiErr.Status,
+			Error:     apiErr.Code,
+			Message:   apiErr.Message,
+			Data:      apiErr.Data,
+			Fields:    apiErr.Fields,
+			RequestID: requestID,
+			TraceID:   tracing.TraceID(c.UserContext()),
 		})
 	}
 }
@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+
 	// "backend/db"
 	"firebase.google.com/go/v4/db"
 	// "backend/errors"
@@ -25,34 +27,25 @@ import (
 // @Success 200 {object} Game
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /games/:lobbyCode/players/:playerName/ready [post]
+// @Router /v1/lobbies/{lobbyCode}/players/{playerName}/ready [post]
 func SetPlayerReady(c *fiber.Ctx, dbClient *db.Client) error {
 	playerName := c.Params("playerName")
 
 	gameID, err := GetGameIDByLobbyCode(c, dbClient)
 	if err != nil {
-		// Return a fiber error with appropriate status code and message
-		return fiber.NewError(fiber.StatusNotFound, "game not found")
-	}
-
-	gameRef := dbClient.NewRef("games/" + gameID)
-
-	game := &model.Game{}
-	if err := gameRef.Get(context.Background(), game); err != nil {
-		// Return a fiber error with appropriate status code and message
-		return fiber.NewError(fiber.StatusNotFound, "game not found")
+		return err
 	}
 
-	for _, player := range game.Players {
-		if player.Name == playerName {
-			player.LobbyStatus = true
-			break
+	game, err := updateGame(c.UserContext(), dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+		player := game.PlayerNamed(playerName)
+		if player == nil {
+			return fmt.Errorf("%w: %s", model.ErrPlayerNotFound, playerName)
 		}
-	}
-
-	if err := gameRef.Set(context.Background(), game); err != nil {
-		// Return a fiber error with appropriate status code and message
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to save game to Firebase RTDB")
+		player.LobbyStatus = true
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(game)
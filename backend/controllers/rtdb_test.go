@@ -0,0 +1,400 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"backend/lobbycode"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/db"
+)
+
+// fakeRTDB is an in-memory stand-in for the REST API of the Firebase RTDB, covering the reads, writes,
+// transactions and ordered queries made by the controllers
+type fakeRTDB struct {
+	mu   sync.Mutex
+	root interface{}
+	// failing holds the path prefixes whose writes fail
+	failing []string
+	// reads counts the reads of each path
+	reads  map[string]int
+	pushed int
+}
+
+// newTestDB starts a fake RTDB for the test and returns a client connected to it
+func newTestDB(t *testing.T) (*db.Client, *fakeRTDB) {
+	t.Helper()
+	fake := &fakeRTDB{reads: map[string]int{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	// A database URL without a scheme is taken for an emulator, which needs no credentials
+	host := strings.TrimPrefix(server.URL, "http://127.0.0.1")
+	app, err := firebase.NewApp(context.Background(), &firebase.Config{
+		ProjectID:   "test",
+		DatabaseURL: "localhost" + host + "?ns=test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := app.Database(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := InitLobbyCodes(client, lobbycode.Config{Style: lobbycode.StyleChars}); err != nil {
+		t.Fatal(err)
+	}
+	return client, fake
+}
+
+// set writes a value at path, as if written by another server
+func (f *fakeRTDB) set(t *testing.T, path string, value interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.root = setAt(f.root, splitPath(path), v)
+}
+
+// get reads the value at path into v
+func (f *fakeRTDB) get(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	f.mu.Lock()
+	raw, err := json.Marshal(getAt(f.root, splitPath(path)))
+	f.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failWrites makes the writes under path fail
+func (f *fakeRTDB) failWrites(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failing = append(f.failing, strings.Join(splitPath(path), "/"))
+}
+
+// readsOf returns the number of reads of path so far
+func (f *fakeRTDB) readsOf(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads[strings.Join(splitPath(path), "/")]
+}
+
+func (f *fakeRTDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	segs := splitPath(strings.TrimSuffix(r.URL.Path, ".json"))
+	path := strings.Join(segs, "/")
+
+	var body interface{}
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	var written []string
+	switch r.Method {
+	case http.MethodPatch:
+		children, _ := body.(map[string]interface{})
+		for key := range children {
+			written = append(written, strings.Join(append(append([]string{}, segs...), splitPath(key)...), "/"))
+		}
+	case http.MethodPut, http.MethodPost, http.MethodDelete:
+		written = []string{path}
+	}
+	for _, p := range written {
+		for _, prefix := range f.failing {
+			if p == prefix || strings.HasPrefix(p, prefix+"/") || prefix == "" {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "write failed"})
+				return
+			}
+		}
+	}
+
+	current := getAt(f.root, segs)
+	switch r.Method {
+	case http.MethodGet:
+		f.reads[path]++
+		if r.Header.Get("X-Firebase-ETag") == "true" {
+			w.Header().Set("ETag", etagOf(current))
+		}
+		if r.URL.Query().Has("orderBy") {
+			result, err := queryChildren(current, r.URL.Query())
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			current = result
+		}
+		writeJSON(w, http.StatusOK, current)
+	case http.MethodPut:
+		if match := r.Header.Get("If-Match"); match != "" && match != etagOf(current) {
+			w.Header().Set("ETag", etagOf(current))
+			writeJSON(w, http.StatusPreconditionFailed, current)
+			return
+		}
+		f.root = setAt(f.root, segs, body)
+		writeJSON(w, http.StatusOK, body)
+	case http.MethodPatch:
+		children, ok := body.(map[string]interface{})
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "update needs an object"})
+			return
+		}
+		for key, value := range children {
+			f.root = setAt(f.root, append(append([]string{}, segs...), splitPath(key)...), value)
+		}
+		writeJSON(w, http.StatusOK, body)
+	case http.MethodPost:
+		f.pushed++
+		key := fmt.Sprintf("-push%08d", f.pushed)
+		f.root = setAt(f.root, append(segs, key), body)
+		writeJSON(w, http.StatusOK, map[string]string{"name": key})
+	case http.MethodDelete:
+		f.root = setAt(f.root, segs, nil)
+		writeJSON(w, http.StatusOK, nil)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func splitPath(path string) []string {
+	var segs []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+func etagOf(v interface{}) string {
+	raw, _ := json.Marshal(v)
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// getAt returns the value at the path below node, nil if there is none
+func getAt(node interface{}, segs []string) interface{} {
+	for _, seg := range segs {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			node = n[seg]
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil
+			}
+			node = n[i]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// setAt returns node with the value at the path below it replaced. Like the RTDB, it keeps no nulls
+// and no empty objects.
+func setAt(node interface{}, segs []string, value interface{}) interface{} {
+	if len(segs) == 0 {
+		return prune(value)
+	}
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+		if list, isList := node.([]interface{}); isList {
+			for i, v := range list {
+				m[strconv.Itoa(i)] = v
+			}
+		}
+	}
+	if child := setAt(m[segs[0]], segs[1:], value); child != nil {
+		m[segs[0]] = child
+	} else {
+		delete(m, segs[0])
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+func prune(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if child = prune(child); child == nil {
+				delete(v, key)
+			} else {
+				v[key] = child
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		for i, child := range v {
+			v[i] = prune(child)
+		}
+	}
+	return value
+}
+
+// queryChildren applies the orderBy, range and limit params of a query to the children of node
+func queryChildren(node interface{}, params map[string][]string) (interface{}, error) {
+	children, ok := node.(map[string]interface{})
+	if !ok {
+		return node, nil
+	}
+	param := func(name string) (interface{}, bool, error) {
+		values, ok := params[name]
+		if !ok {
+			return nil, false, nil
+		}
+		var v interface{}
+		err := json.Unmarshal([]byte(values[0]), &v)
+		return v, true, err
+	}
+
+	orderBy, _, err := param("orderBy")
+	if err != nil {
+		return nil, err
+	}
+	order, _ := orderBy.(string)
+	valueOf := func(key string) interface{} {
+		switch order {
+		case "$key":
+			return key
+		case "$value":
+			return children[key]
+		default:
+			return getAt(children[key], splitPath(order))
+		}
+	}
+
+	keys := make([]string, 0, len(children))
+	for key := range children {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if c := compareValues(valueOf(keys[i]), valueOf(keys[j])); c != 0 {
+			return c < 0
+		}
+		return keys[i] < keys[j]
+	})
+
+	var filtered []string
+	for _, key := range keys {
+		keep := true
+		for _, bound := range []struct {
+			name string
+			ok   func(c int) bool
+		}{
+			{"startAt", func(c int) bool { return c >= 0 }},
+			{"endAt", func(c int) bool { return c <= 0 }},
+			{"equalTo", func(c int) bool { return c == 0 }},
+		} {
+			v, set, err := param(bound.name)
+			if err != nil {
+				return nil, err
+			}
+			if set && !bound.ok(compareValues(valueOf(key), v)) {
+				keep = false
+			}
+		}
+		if keep {
+			filtered = append(filtered, key)
+		}
+	}
+
+	if first, err := strconv.Atoi(firstOf(params["limitToFirst"])); err == nil && first < len(filtered) {
+		filtered = filtered[:first]
+	}
+	if last, err := strconv.Atoi(firstOf(params["limitToLast"])); err == nil && last < len(filtered) {
+		filtered = filtered[len(filtered)-last:]
+	}
+
+	result := map[string]interface{}{}
+	for _, key := range filtered {
+		result[key] = children[key]
+	}
+	return result, nil
+}
+
+func firstOf(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// compareValues orders values like the RTDB: nulls, false, true, numbers, strings, then objects
+func compareValues(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v := v.(type) {
+		case nil:
+			return 0
+		case bool:
+			if v {
+				return 2
+			}
+			return 1
+		case float64:
+			return 3
+		case string:
+			return 4
+		default:
+			return 5
+		}
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
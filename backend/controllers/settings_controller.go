@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+
+	"firebase.google.com/go/v4/db"
+
+	"backend/logging"
+	"backend/model"
+	"backend/responses"
+	"backend/validate"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SettingsUpdate represents the request body for the update settings endpoint. Fields left out keep their current value.
+type SettingsUpdate struct {
+	MaxPlayers    *int    `json:"MaxPlayers" validate:"omitempty,min=3,max=12"`
+	RuleSet       *string `json:"RuleSet" validate:"oneof=classic wild"`
+	StartingChips *int    `json:"StartingChips" validate:"omitempty,min=1,max=10"`
+	TurnTimeout   *int    `json:"TurnTimeout" validate:"min=0,max=600"`
+	Visibility    *string `json:"Visibility" validate:"oneof=public unlisted private"`
+	BotFill       *bool   `json:"BotFill"`
+	// Password sets a new lobby password, or removes it when empty
+	Password *string `json:"Password" validate:"maxbytes=72"`
+}
+
+// apply returns the settings with the update applied
+func (u *SettingsUpdate) apply(settings model.Settings) model.Settings {
+	if u.MaxPlayers != nil {
+		settings.MaxPlayers = *u.MaxPlayers
+	}
+	if u.RuleSet != nil {
+		settings.RuleSet = *u.RuleSet
+	}
+	if u.StartingChips != nil {
+		settings.StartingChips = *u.StartingChips
+	}
+	if u.TurnTimeout != nil {
+		settings.TurnTimeout = *u.TurnTimeout
+	}
+	if u.Visibility != nil {
+		settings.Visibility = *u.Visibility
+	}
+	if u.BotFill != nil {
+		settings.BotFill = *u.BotFill
+	}
+	return settings
+}
+
+// UpdateGameSettings lets the host change the lobby settings before the game starts
+// @Summary Update lobby settings
+// @Description Updates the settings of the lobby identified by the provided lobby code. Only the host may change them, and only before the game starts. Every player's chips are reset to the new starting chips.
+// @Tags Games
+// @Accept json
+// @Produce json
+// @Param lobbyCode path string true "Lobby code"
+// @Param settings body SettingsUpdate true "Settings to change"
+// @Success 200 {object} Game
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /v1/lobbies/{lobbyCode}/settings [put]
+func UpdateGameSettings(c *fiber.Ctx, dbClient *db.Client) error {
+	update := &SettingsUpdate{}
+	if err := validate.Body(c, update); err != nil {
+		return err
+	}
+
+	gameID, err := GetGameIDByLobbyCode(c, dbClient)
+	if err != nil {
+		return err
+	}
+
+	userID, _ := c.Locals("user").(string)
+	game, err := updateGame(c.UserContext(), dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+		if game.Creator == nil || game.Creator.UserID != userID {
+			return responses.NewError(fiber.StatusForbidden, "host_only", "Only the host can change the lobby settings")
+		}
+		if !game.IsOpenLobby() {
+			return model.ErrGameStarted
+		}
+
+		hasPassword := game.HasPassword
+		if update.Password != nil {
+			hasPassword = *update.Password != ""
+		}
+
+		settings := update.apply(game.Settings.WithDefaults())
+		if err := game.ApplySettings(settings); err != nil {
+			return err
+		}
+
+		if update.Password != nil {
+			var err error
+			if hasPassword {
+				err = setLobbyPassword(ctx, dbClient, gameID, *update.Password)
+			} else {
+				err = DeleteLobbyPassword(ctx, dbClient, gameID)
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to update lobby password in Firebase RTDB", logging.KeyError, err)
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to update lobby password in Firebase RTDB")
+			}
+			game.HasPassword = hasPassword
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(game)
+}
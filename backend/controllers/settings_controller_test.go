@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	"backend/model"
+	"backend/responses"
+	"backend/validate"
+)
+
+func TestSettingsValidation(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	players := []*PlayerRequest{{Name: "Ann"}}
+
+	tests := []struct {
+		name string
+		body interface{}
+		// want is the field and rule of each expected failure
+		want [][2]string
+	}{
+		{name: "create with defaults", body: &CreateGameRequest{Players: players}},
+		{
+			name: "create within limits",
+			body: &CreateGameRequest{Players: players, Settings: model.Settings{MaxPlayers: 12, StartingChips: 10, TurnTimeout: 600}},
+		},
+		{
+			name: "create out of range",
+			body: &CreateGameRequest{Players: players, Settings: model.Settings{MaxPlayers: 2, StartingChips: 11, TurnTimeout: -1, RuleSet: "house"}},
+			want: [][2]string{{"MaxPlayers", "min"}, {"RuleSet", "oneof"}, {"StartingChips", "max"}, {"TurnTimeout", "min"}},
+		},
+		{name: "update nothing", body: &SettingsUpdate{}},
+		{name: "update back to the defaults", body: &SettingsUpdate{MaxPlayers: intPtr(0), StartingChips: intPtr(0), TurnTimeout: intPtr(0)}},
+		{
+			name: "update out of range",
+			body: &SettingsUpdate{MaxPlayers: intPtr(13), StartingChips: intPtr(-2), TurnTimeout: intPtr(601)},
+			want: [][2]string{{"MaxPlayers", "max"}, {"StartingChips", "min"}, {"TurnTimeout", "max"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []responses.FieldError
+			if err := validate.Check(tt.body); err != nil {
+				var apiErr *responses.Error
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("got error %v, want field errors", err)
+				}
+				fields = apiErr.Fields
+			}
+			if len(fields) != len(tt.want) {
+				t.Fatalf("got field errors %+v, want %v", fields, tt.want)
+			}
+			for i, want := range tt.want {
+				if fields[i].Field != want[0] || fields[i].Rule != want[1] {
+					t.Errorf("field error %d is %+v, want field %s rule %s", i, fields[i], want[0], want[1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import "sync"
+
+// ShutdownReason is sent to clients whose live game updates are cut off by a shutdown
+const ShutdownReason = "Server is shutting down, please reconnect"
+
+var (
+	shuttingDown = make(chan struct{})
+	shutdownOnce sync.Once
+)
+
+// BeginShutdown marks the server as shutting down. Live game streams watching ShuttingDown end
+// with ShutdownReason and the readiness probe starts failing.
+func BeginShutdown() {
+	shutdownOnce.Do(func() {
+		close(shuttingDown)
+	})
+}
+
+// ShuttingDown is closed once the server starts shutting down
+func ShuttingDown() <-chan struct{} {
+	return shuttingDown
+}
+
+// IsShuttingDown reports whether BeginShutdown was called
+func IsShuttingDown() bool {
+	select {
+	case <-shuttingDown:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"backend/config"
+
+	"google.golang.org/api/option"
+)
+
+// CredentialsProvider supplies the credentials the Firebase app authenticates with
+type CredentialsProvider interface {
+	// Name describes where the credentials come from, for logs
+	Name() string
+	// ClientOptions returns the options passed to firebase.NewApp
+	ClientOptions(ctx context.Context) ([]option.ClientOption, error)
+}
+
+// NewCredentialsProvider returns the provider selected by cfg. pg is only used by the postgres source.
+func NewCredentialsProvider(cfg config.Firebase, pg *sql.DB) (CredentialsProvider, error) {
+	switch cfg.CredentialsSource {
+	case config.CredentialsPostgres:
+		return &PostgresCredentials{DB: pg}, nil
+	case config.CredentialsFile:
+		return &FileCredentials{Path: cfg.CredentialsFile}, nil
+	case config.CredentialsEnv:
+		return &Base64Credentials{Value: cfg.CredentialsBase64}, nil
+	case config.CredentialsDefault:
+		return &DefaultCredentials{}, nil
+	}
+	return nil, fmt.Errorf("unknown Firebase credentials source %q", cfg.CredentialsSource)
+}
+
+// PostgresCredentials reads the service account from the firebase table
+type PostgresCredentials struct {
+	DB *sql.DB
+}
+
+func (p *PostgresCredentials) Name() string {
+	return "postgres"
+}
+
+func (p *PostgresCredentials) ClientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	if p.DB == nil {
+		return nil, fmt.Errorf("no PostgreSQL connection to read Firebase credentials from")
+	}
+	var jsonVal []byte
+	if err := p.DB.QueryRowContext(ctx, "SELECT credentials FROM firebase").Scan(&jsonVal); err != nil {
+		return nil, fmt.Errorf("failed to retrieve Firebase credentials: %w", err)
+	}
+	return credentialsJSON(jsonVal)
+}
+
+// FileCredentials reads the service account from a JSON file
+type FileCredentials struct {
+	Path string
+}
+
+func (f *FileCredentials) Name() string {
+	return "file " + f.Path
+}
+
+func (f *FileCredentials) ClientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	jsonVal, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Firebase credentials: %w", err)
+	}
+	return credentialsJSON(jsonVal)
+}
+
+// Base64Credentials reads the service account from base64 encoded JSON, as set in an environment variable
+type Base64Credentials struct {
+	Value string
+}
+
+func (b *Base64Credentials) Name() string {
+	return "environment"
+}
+
+func (b *Base64Credentials) ClientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	// Accept both padded and unpadded encodings, and line breaks added by base64 tools
+	value := strings.TrimRight(strings.Join(strings.Fields(b.Value), ""), "=")
+	jsonVal, err := base64.RawStdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("firebase credentials are not valid base64: %w", err)
+	}
+	return credentialsJSON(jsonVal)
+}
+
+// DefaultCredentials uses Google Application Default Credentials: GOOGLE_APPLICATION_CREDENTIALS,
+// the gcloud user credentials or the metadata server of the hosting platform
+type DefaultCredentials struct{}
+
+func (d *DefaultCredentials) Name() string {
+	return "application default credentials"
+}
+
+func (d *DefaultCredentials) ClientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	// firebase.NewApp falls back to the default credentials when given no credentials option
+	return nil, nil
+}
+
+// credentialsJSON checks that jsonVal is a service account and turns it into a client option
+func credentialsJSON(jsonVal []byte) ([]option.ClientOption, error) {
+	var credentials FirebaseCredentials
+	if err := json.Unmarshal(jsonVal, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Firebase credentials: %w", err)
+	}
+	if credentials.Type == "" || credentials.ProjectID == "" {
+		return nil, fmt.Errorf("firebase credentials are missing the type or project_id of the service account")
+	}
+	return []option.ClientOption{option.WithCredentialsJSON(jsonVal)}, nil
+}
@@ -3,18 +3,31 @@ package db
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"backend/config"
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/auth"
 	"firebase.google.com/go/v4/db"
 	_ "github.com/lib/pq"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
+// firebaseScopes are the OAuth2 scopes the Admin SDK requests for its own HTTP clients
+var firebaseScopes = []string{
+	"https://www.googleapis.com/auth/cloud-platform",
+	"https://www.googleapis.com/auth/datastore",
+	"https://www.googleapis.com/auth/devstorage.full_control",
+	"https://www.googleapis.com/auth/firebase",
+	"https://www.googleapis.com/auth/identitytoolkit",
+	"https://www.googleapis.com/auth/userinfo.email",
+}
+
 type FirebaseCredentials struct {
 	Type                    string `json:"type"`
 	ProjectID               string `json:"project_id"`
@@ -30,62 +43,85 @@ type FirebaseCredentials struct {
 
 var AuthClient *auth.Client // <-- add this
 var (
-	postgresPassword string
-	PgDb             *sql.DB
-	FbDb             *db.Client
+	PgDb *sql.DB
+	FbDb *db.Client
 )
 
 var DbClient *db.Client
 
-func Init() {
-	postgresPassword = os.Getenv("POSTGRES_PASSWORD")
-	if postgresPassword == "" {
-		log.Fatalf("Failed to get POSTGRES_PASSWORD environment variable")
+// OpenPostgres connects to PostgreSQL, unless it is already connected
+func OpenPostgres(cfg *config.Config) error {
+	if PgDb != nil {
+		return nil
+	}
+	pg, err := sql.Open("postgres", cfg.Postgres.DSN())
+	if err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
+	PgDb = pg
+	return nil
+}
 
-	psqlInfo := fmt.Sprintf("postgresql://postgres:%s@roundhouse.proxy.rlwy.net:20318/railway", postgresPassword)
+// Init connects to PostgreSQL if the Firebase credentials are read from there, and sets up the Firebase RTDB and Auth clients
+func Init(cfg *config.Config) error {
 	var err error
-	PgDb, err = sql.Open("postgres", psqlInfo)
-	if err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	if cfg.UsesPostgres() {
+		if err := OpenPostgres(cfg); err != nil {
+			return err
+		}
 	}
 
-	var jsonVal []byte
-	err = PgDb.QueryRow("SELECT credentials FROM firebase").Scan(&jsonVal)
+	provider, err := NewCredentialsProvider(cfg.Firebase, PgDb)
 	if err != nil {
-		log.Fatalf("Failed to retrieve Firebase credentials: %v", err)
+		return fmt.Errorf("failed to set up Firebase credentials: %w", err)
 	}
-
-	var credentials FirebaseCredentials
-	err = json.Unmarshal(jsonVal, &credentials)
+	opts, err := provider.ClientOptions(context.Background())
 	if err != nil {
-		log.Fatalf("Failed to unmarshal Firebase credentials: %v", err)
+		return fmt.Errorf("failed to load Firebase credentials from %s: %w", provider.Name(), err)
 	}
+	slog.Info("Using Firebase credentials", "source", provider.Name())
 
-	optBytes, err := json.Marshal(credentials)
+	// Send the Firebase calls through a client that times and traces the RTDB requests
+	httpClient, err := instrumentedClient(context.Background(), cfg.Firebase.DatabaseURL, opts)
 	if err != nil {
-		log.Fatalf("Failed to marshal Firebase credentials: %v", err)
+		return fmt.Errorf("failed to set up Firebase HTTP client: %w", err)
 	}
+	opts = append(opts, option.WithHTTPClient(httpClient))
 
-	opt := option.WithCredentialsJSON(optBytes)
-
-	fbApp, err := firebase.NewApp(context.Background(), nil, opt)
+	fbApp, err := firebase.NewApp(context.Background(), nil, opts...)
 	if err != nil {
-		log.Fatalf("Failed to initialize Firebase app: %v", err)
+		return fmt.Errorf("failed to initialize Firebase app: %w", err)
 	}
 
-	DbClient, err = fbApp.DatabaseWithURL(context.Background(), "https://lcr-webapp-default-rtdb.firebaseio.com/")
+	DbClient, err = fbApp.DatabaseWithURL(context.Background(), cfg.Firebase.DatabaseURL)
 	if err != nil {
-		log.Fatalf("Failed to initialize Firebase RTDB client: %v", err)
+		return fmt.Errorf("failed to initialize Firebase RTDB client: %w", err)
 	}
 
 	AuthClient, err = fbApp.Auth(context.Background())
 	if err != nil {
-		log.Fatalf("Failed to initialize Firebase Auth client: %v", err)
+		return fmt.Errorf("failed to initialize Firebase Auth client: %w", err)
+	}
+	return nil
+}
+
+// instrumentedClient returns an HTTP client authenticated with opts that times and traces RTDB requests
+func instrumentedClient(ctx context.Context, databaseURL string, opts []option.ClientOption) (*http.Client, error) {
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	base := &storeTransport{base: http.DefaultTransport, host: parsed.Host}
+	transport, err := htransport.NewTransport(ctx, base, append([]option.ClientOption{option.WithScopes(firebaseScopes...)}, opts...)...)
+	if err != nil {
+		return nil, err
 	}
+	return &http.Client{Transport: transport}, nil
 }
 
 // CloseDbConnections - This function should be deferred in your main function to properly close database connections when your application stops
 func ClosePgConnection() {
-	PgDb.Close()
+	if PgDb != nil {
+		PgDb.Close()
+	}
 }
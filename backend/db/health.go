@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+)
+
+// healthPath is read by PingFirebase. It does not need to exist, reading it only proves the RTDB answers.
+const healthPath = "health"
+
+// healthCheckUID is looked up by PingAuth. Any user ID works, the lookup is expected to find nobody.
+const healthCheckUID = "lcr-health-check"
+
+// authCheckTTL is how long PingAuth reuses its last result, so frequent probes do not each look up a user
+const authCheckTTL = 30 * time.Second
+
+// authCheck is the last result of PingAuth
+var authCheck struct {
+	sync.Mutex
+	at  time.Time
+	err error
+}
+
+// PingFirebase checks that the Firebase RTDB is reachable and accepts our credentials
+func PingFirebase(ctx context.Context) error {
+	if DbClient == nil {
+		return fmt.Errorf("firebase RTDB client is not initialized")
+	}
+	var value interface{}
+	return DbClient.NewRef(healthPath).Get(ctx, &value)
+}
+
+// PingAuth checks that Firebase Auth is reachable and accepts our credentials. The result is reused for
+// authCheckTTL, unless the check ran out of time.
+func PingAuth(ctx context.Context) error {
+	authCheck.Lock()
+	defer authCheck.Unlock()
+	if !authCheck.at.IsZero() && time.Since(authCheck.at) < authCheckTTL {
+		return authCheck.err
+	}
+
+	err := pingAuth(ctx)
+	if ctx.Err() == nil {
+		authCheck.at, authCheck.err = time.Now(), err
+	}
+	return err
+}
+
+func pingAuth(ctx context.Context) error {
+	if AuthClient == nil {
+		return fmt.Errorf("firebase Auth client is not initialized")
+	}
+	_, err := AuthClient.GetUser(ctx, healthCheckUID)
+	if err != nil && !auth.IsUserNotFound(err) {
+		return err
+	}
+	return nil
+}
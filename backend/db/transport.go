@@ -0,0 +1,77 @@
+package db
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/metrics"
+	"backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// storeTransport times and traces the requests sent to the RTDB at host. Requests to other hosts, such as
+// Firebase Auth, pass through untouched.
+type storeTransport struct {
+	base http.RoundTripper
+	host string
+}
+
+func (t *storeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+
+	operation, node := storeOperation(req.Method), storeNode(req.URL.Path)
+	ctx, span := tracing.Tracer().Start(req.Context(), "rtdb "+operation+" "+node,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "firebase_rtdb"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.path", strings.TrimSuffix(req.URL.Path, ".json")),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	status := "error"
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		status = strconv.Itoa(res.StatusCode)
+		span.SetAttributes(attribute.Int("http.status_code", res.StatusCode))
+		if res.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, res.Status)
+		}
+	}
+	metrics.ObserveStoreRequest(operation, node, status, time.Since(start))
+	return res, err
+}
+
+// storeOperation names the RTDB operation behind an HTTP method
+func storeOperation(method string) string {
+	switch method {
+	case http.MethodGet:
+		return "read"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "write"
+	}
+}
+
+// storeNode returns the top-level node of an RTDB REST path such as /games/abc.json
+func storeNode(path string) string {
+	node, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	node = strings.TrimSuffix(node, ".json")
+	if node == "" {
+		return "root"
+	}
+	return node
+}
@@ -0,0 +1,161 @@
+// Package idempotency replays the stored response of a request sent again with the same
+// Idempotency-Key, so clients can safely retry requests that are not idempotent, such as rolling
+// the dice.
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"backend/responses"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Header is the request header holding the key chosen by the client
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from the store
+const ReplayedHeader = "Idempotent-Replayed"
+
+// KeyRules are the validate rules of the Idempotency-Key header
+const KeyRules = "max=255"
+
+// Error codes of requests whose key cannot be used
+const (
+	// CodeInUse is returned while the first request with the key is still being handled
+	CodeInUse = "idempotency_key_in_use"
+	// CodeReused is returned when the key was already used for a different request
+	CodeReused = "idempotency_key_reused"
+)
+
+// response is a stored response
+type response struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// entry is the state of a key: in flight until response is set
+type entry struct {
+	fingerprint string
+	response    *response
+	expires     time.Time
+}
+
+// Store keeps the responses of requests with an Idempotency-Key in memory for a TTL. It is safe for
+// concurrent use, but not shared between server instances.
+type Store struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	entries    map[string]*entry
+	lastPurged time.Time
+}
+
+// NewStore creates a store keeping responses for ttl
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, entries: map[string]*entry{}, lastPurged: time.Now()}
+}
+
+// begin reserves key for a request, or returns the response stored for it
+func (s *Store) begin(key, fingerprint string, now time.Time) (*response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purge(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		switch {
+		case e.fingerprint != fingerprint:
+			return nil, responses.NewError(fiber.StatusUnprocessableEntity, CodeReused, "Idempotency-Key was already used for a different request")
+		case e.response == nil:
+			return nil, responses.NewError(fiber.StatusConflict, CodeInUse, "A request with this Idempotency-Key is still being handled")
+		}
+		return e.response, nil
+	}
+	s.entries[key] = &entry{fingerprint: fingerprint, expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+// complete stores the response of the request holding key
+func (s *Store) complete(key string, resp *response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.response = resp
+	}
+}
+
+// release frees key, so the request can be sent again and handled anew
+func (s *Store) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// purge drops the expired entries, at most once per minute. s.mu must be held.
+func (s *Store) purge(now time.Time) {
+	if now.Sub(s.lastPurged) < time.Minute {
+		return
+	}
+	s.lastPurged = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Middleware handles the requests carrying an Idempotency-Key: the first one is handled and its
+// response stored, repeats get the stored response. Keys are scoped by scope, e.g. the user, and by
+// route. Responses with a 5xx status are not stored, so the request can be retried. Errors returned
+// by the handlers are written by the app's error handler here, to store the response sent.
+func (s *Store) Middleware(scope func(c *fiber.Ctx) string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		clientKey := c.Get(Header)
+		if clientKey == "" {
+			return c.Next()
+		}
+
+		key := scope(c) + "\x00" + c.Method() + "\x00" + c.Route().Path + "\x00" + clientKey
+		sum := sha256.Sum256(append([]byte(c.Path()+"\x00"), c.Body()...))
+		stored, err := s.begin(key, hex.EncodeToString(sum[:]), time.Now())
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			c.Set(ReplayedHeader, "true")
+			if stored.contentType != "" {
+				c.Set(fiber.HeaderContentType, stored.contentType)
+			}
+			return c.Status(stored.status).Send(stored.body)
+		}
+
+		// Frees the key if the handler panics, or it would answer 409 until it expires
+		completed := false
+		defer func() {
+			if !completed {
+				s.release(key)
+			}
+		}()
+
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			return nil
+		}
+		completed = true
+		s.complete(key, &response{
+			status:      status,
+			contentType: string(c.Response().Header.ContentType()),
+			body:        append([]byte(nil), c.Response().Body()...),
+		})
+		return nil
+	}
+}
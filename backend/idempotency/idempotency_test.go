@@ -0,0 +1,115 @@
+package idempotency
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"backend/responses"
+)
+
+func TestBegin(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	stored := &response{status: 200, contentType: "application/json", body: []byte(`{"ok":true}`)}
+
+	type step struct {
+		// after is the time of the request since start
+		after       time.Duration
+		fingerprint string
+		// complete stores the response once the request has begun
+		complete bool
+		// release frees the key once the request has begun
+		release    bool
+		wantReplay bool
+		wantCode   string
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "repeat replays the response",
+			steps: []step{
+				{fingerprint: "roll", complete: true},
+				{after: time.Second, fingerprint: "roll", wantReplay: true},
+			},
+		},
+		{
+			name: "repeat while in flight",
+			steps: []step{
+				{fingerprint: "roll"},
+				{after: time.Second, fingerprint: "roll", wantCode: CodeInUse},
+			},
+		},
+		{
+			name: "key reused for another request",
+			steps: []step{
+				{fingerprint: "roll", complete: true},
+				{after: time.Second, fingerprint: "leave", wantCode: CodeReused},
+			},
+		},
+		{
+			name: "released key is handled anew",
+			steps: []step{
+				{fingerprint: "roll", release: true},
+				{after: time.Second, fingerprint: "roll"},
+			},
+		},
+		{
+			name: "expired key is handled anew",
+			steps: []step{
+				{fingerprint: "roll", complete: true},
+				{after: time.Hour, fingerprint: "leave"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStore(time.Hour)
+			for i, st := range tt.steps {
+				resp, err := s.begin("key", st.fingerprint, start.Add(st.after))
+				var apiErr *responses.Error
+				switch {
+				case st.wantCode != "":
+					if !errors.As(err, &apiErr) || apiErr.Code != st.wantCode {
+						t.Fatalf("step %d: got error %v, want %s", i, err, st.wantCode)
+					}
+					continue
+				case err != nil:
+					t.Fatalf("step %d: got error %v", i, err)
+				case st.wantReplay != (resp != nil):
+					t.Fatalf("step %d: got response %+v, want replayed %v", i, resp, st.wantReplay)
+				}
+				if st.complete {
+					s.complete("key", stored)
+				}
+				if st.release {
+					s.release("key")
+				}
+			}
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewStore(time.Minute)
+	s.lastPurged = now
+	if _, err := s.begin("old", "roll", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.begin("new", "roll", now.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	s.purge(now.Add(time.Minute))
+	s.mu.Unlock()
+	if _, ok := s.entries["old"]; ok {
+		t.Error("expired key old was kept")
+	}
+	if _, ok := s.entries["new"]; !ok {
+		t.Error("key new was dropped before it expired")
+	}
+}
@@ -0,0 +1,194 @@
+package invite
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+)
+
+// redemptionsPath is the RTDB node recording which single-use invites have been used, keyed by nonce
+const redemptionsPath = "invites"
+
+var (
+	// ErrInvalid is returned for tokens that are malformed or carry a bad signature
+	ErrInvalid = errors.New("invalid invite")
+	// ErrExpired is returned for tokens past their expiry
+	ErrExpired = errors.New("invite has expired")
+	// ErrUsed is returned when a single-use invite is redeemed a second time
+	ErrUsed = errors.New("invite has already been used")
+)
+
+// Claims is the content of an invite token
+type Claims struct {
+	GameID    string `json:"g"`
+	LobbyCode string `json:"c"`
+	ExpiresAt int64  `json:"e"`
+	Nonce     string `json:"n"`
+	SingleUse bool   `json:"s,omitempty"`
+}
+
+// Expiry returns the time the invite stops being valid
+func (c *Claims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0).UTC()
+}
+
+// Signer creates and verifies invite tokens
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner creates a signer using the given secret
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// NewRandomSigner creates a signer using a random secret. Its invites stop working when the server restarts.
+func NewRandomSigner() (*Signer, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return NewSigner(secret), nil
+}
+
+// Create returns a signed token inviting to the game until ttl has passed
+func (s *Signer) Create(gameID, lobbyCode string, ttl time.Duration, singleUse bool) (string, *Claims, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	claims := &Claims{
+		GameID:    gameID,
+		LobbyCode: lobbyCode,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+		Nonce:     hex.EncodeToString(nonce),
+		SingleUse: singleUse,
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.sign(encoded), claims, nil
+}
+
+// Verify checks the signature and expiry of a token and returns its claims
+func (s *Signer) Verify(token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, ErrInvalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil || claims.GameID == "" {
+		return nil, ErrInvalid
+	}
+	if time.Now().After(claims.Expiry()) {
+		return nil, ErrExpired
+	}
+	return claims, nil
+}
+
+func (s *Signer) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// LooksLikeToken reports whether value is shaped like an invite token rather than a lobby code
+func LooksLikeToken(value string) bool {
+	return strings.Contains(value, ".")
+}
+
+// redemption is the record kept for a used single-use invite
+type redemption struct {
+	GameID    string    `json:"GameID"`
+	UsedBy    string    `json:"UsedBy"`
+	UsedAt    time.Time `json:"UsedAt"`
+	ExpiresAt time.Time `json:"ExpiresAt"`
+}
+
+// CheckUnused returns ErrUsed if a single-use invite has been redeemed by another user. Joining checks it
+// before saving the player, and only redeems the invite once the player is saved.
+func CheckUnused(ctx context.Context, dbClient *db.Client, claims *Claims, userID string) error {
+	if !claims.SingleUse {
+		return nil
+	}
+	var current *redemption
+	if err := dbClient.NewRef(redemptionsPath+"/"+claims.Nonce).Get(ctx, &current); err != nil {
+		return err
+	}
+	if current != nil && current.UsedBy != userID {
+		return ErrUsed
+	}
+	return nil
+}
+
+// Redeem marks a single-use invite as used by the given user. It returns ErrUsed if someone else used it first.
+// Invites that are not single-use are always accepted.
+func Redeem(ctx context.Context, dbClient *db.Client, claims *Claims, userID string) error {
+	if !claims.SingleUse {
+		return nil
+	}
+
+	used := false
+	err := dbClient.NewRef(redemptionsPath+"/"+claims.Nonce).Transaction(ctx, func(node db.TransactionNode) (interface{}, error) {
+		var current *redemption
+		if err := node.Unmarshal(&current); err != nil {
+			return nil, err
+		}
+		if current != nil {
+			used = current.UsedBy != userID
+			return current, nil
+		}
+		used = false
+		return &redemption{
+			GameID:    claims.GameID,
+			UsedBy:    userID,
+			UsedAt:    time.Now().UTC(),
+			ExpiresAt: claims.Expiry(),
+		}, nil
+	})
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrUsed
+	}
+	return nil
+}
+
+// DeleteExpired deletes the records of redeemed invites that have expired, which can no longer be used
+// anyway, and returns how many it deleted
+func DeleteExpired(ctx context.Context, dbClient *db.Client, now time.Time) (int, error) {
+	ref := dbClient.NewRef(redemptionsPath)
+	var redemptions map[string]*redemption
+	if err := ref.Get(ctx, &redemptions); err != nil {
+		return 0, err
+	}
+
+	expired := map[string]interface{}{}
+	for nonce, r := range redemptions {
+		if r == nil || !now.Before(r.ExpiresAt) {
+			expired[nonce] = nil
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	return len(expired), ref.Update(ctx, expired)
+}
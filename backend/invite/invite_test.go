@@ -0,0 +1,91 @@
+package invite
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	valid, claims, err := signer.Create("game1", "BRAVE-OTTER", time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, err := signer.Create("game1", "BRAVE-OTTER", -time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _, err := NewSigner([]byte("other")).Create("game1", "BRAVE-OTTER", time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, signature, _ := strings.Cut(valid, ".")
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"g":"game2","c":"BRAVE-OTTER","e":4102444800}`)) + "." + signature
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: valid},
+		{name: "expired", token: expired, wantErr: ErrExpired},
+		{name: "signed with another secret", token: foreign, wantErr: ErrInvalid},
+		{name: "tampered payload", token: tampered, wantErr: ErrInvalid},
+		{name: "missing signature", token: payload, wantErr: ErrInvalid},
+		{name: "empty signature", token: payload + ".", wantErr: ErrInvalid},
+		{name: "garbage", token: "not.a-token", wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := signer.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if *got != *claims {
+				t.Fatalf("got claims %+v, want %+v", got, claims)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	_, first, err := signer.Create("game1", "BRAVE-OTTER", time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, second, err := signer.Create("game1", "BRAVE-OTTER", time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Nonce == second.Nonce {
+		t.Fatalf("two invites share the nonce %s", first.Nonce)
+	}
+	if until := time.Until(first.Expiry()); until <= 59*time.Minute || until > time.Hour {
+		t.Fatalf("invite expires in %s, want about an hour", until)
+	}
+}
+
+func TestLooksLikeToken(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "BRAVE-OTTER", want: false},
+		{value: "K7QM2X", want: false},
+		{value: "eyJnIjoiZ2FtZTEifQ.c2lnbmF0dXJl", want: true},
+	}
+	for _, tt := range tests {
+		if got := LooksLikeToken(tt.value); got != tt.want {
+			t.Errorf("LooksLikeToken(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+
+	"backend/controllers"
+	"backend/invite"
+	"backend/logging"
+	"backend/metrics"
+	"backend/model"
+)
+
+// Config controls how often the janitor runs and when games are collected
+type Config struct {
+	// Interval between two sweeps
+	Interval time.Duration
+	// LobbyIdleTimeout is how long a lobby may go untouched before it is marked abandoned
+	LobbyIdleTimeout time.Duration
+	// GameIdleTimeout is how long a game in progress may go untouched before it is marked abandoned
+	GameIdleTimeout time.Duration
+	// Retention is how long finished games stay in the games tree before they are archived
+	Retention time.Duration
+}
+
+// errNotIdle stops the janitor from abandoning a game that was modified since the sweep read it
+var errNotIdle = errors.New("game is no longer idle")
+
+// errStamped stops the janitor from backfilling the timestamps of a game that got them since the sweep read it
+var errStamped = errors.New("game already has timestamps")
+
+// Report summarizes what a sweep did
+type Report struct {
+	Abandoned int
+	Archived  int
+	Evicted   int
+	// Invites counts the deleted records of expired single-use invites
+	Invites int
+	// Lobbies and InProgress count the open lobbies and games in progress left after the sweep
+	Lobbies    int
+	InProgress int
+}
+
+// Start runs a sweep every cfg.Interval until the returned stop function is called
+func Start(dbClient *db.Client, cfg Config) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+
+		// Sweep once on boot so the lobby index is built straight away
+		sweep(ctx, dbClient, cfg, time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				sweep(ctx, dbClient, cfg, now)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// sweep runs Sweep and logs the outcome
+func sweep(ctx context.Context, dbClient *db.Client, cfg Config, now time.Time) {
+	report, err := Sweep(ctx, dbClient, cfg, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "Janitor sweep failed", logging.KeyError, err)
+		return
+	}
+	metrics.SetGameCounts(report.Lobbies, report.InProgress)
+	if report.Abandoned+report.Archived+report.Evicted+report.Invites > 0 {
+		slog.InfoContext(ctx, "Janitor sweep", "abandoned", report.Abandoned, "archived", report.Archived, "evicted", report.Evicted, "invites", report.Invites)
+	}
+}
+
+// Sweep marks idle lobbies and games abandoned, plays turns whose timer ran out, archives finished games
+// past the retention period, evicts finished or missing games from memory and deletes the records of
+// expired invites
+func Sweep(ctx context.Context, dbClient *db.Client, cfg Config, now time.Time) (Report, error) {
+	var report Report
+
+	var games map[string]*model.Game
+	if err := dbClient.NewRef("games").Get(ctx, &games); err != nil {
+		return report, err
+	}
+
+	for gameID, game := range games {
+		if game == nil {
+			continue
+		}
+
+		// Games stored before timestamps and lobby code reservations existed start their idle clock now
+		// and get their code reserved
+		if game.UpdatedAt.IsZero() {
+			// Saving through the actor of the game stamps it, without writing over a command that ran since
+			// the sweep read it
+			_, err := controllers.UpdateGame(ctx, dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+				if !game.UpdatedAt.IsZero() {
+					return errStamped
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errStamped) {
+				slog.ErrorContext(ctx, "Janitor failed to backfill timestamps", logging.KeyGameID, gameID, logging.KeyError, err)
+			}
+			if !game.GameOver && game.LobbyCode != "" {
+				if ok, err := controllers.LobbyCodes.Adopt(ctx, game.LobbyCode, gameID); err != nil || !ok {
+					slog.WarnContext(ctx, "Janitor failed to reserve lobby code", "lobbyCode", game.LobbyCode, logging.KeyGameID, gameID, logging.KeyError, err)
+				}
+			}
+			continue
+		}
+
+		status := game.Status()
+		var timeout time.Duration
+		switch status {
+		case model.StatusLobby:
+			timeout = cfg.LobbyIdleTimeout
+		case model.StatusInProgress:
+			timeout = cfg.GameIdleTimeout
+			// A turn that ran out while no actor held the game, e.g. across a restart, is played now
+			if deadline, ok := game.TurnDeadline(); ok && !now.Before(deadline) {
+				if err := controllers.ExpireTurn(ctx, dbClient, gameID); err != nil {
+					slog.ErrorContext(ctx, "Janitor failed to play expired turn", logging.KeyGameID, gameID, logging.KeyError, err)
+				}
+				continue
+			}
+		default:
+			if game.IdleFor(now) >= cfg.Retention {
+				if err := archive(ctx, dbClient, gameID, game); err != nil {
+					slog.ErrorContext(ctx, "Janitor failed to archive game", logging.KeyGameID, gameID, logging.KeyError, err)
+					continue
+				}
+				controllers.ReleaseLobbyCode(ctx, gameID, game)
+				delete(games, gameID)
+				report.Archived++
+			}
+			continue
+		}
+		if game.IdleFor(now) < timeout {
+			continue
+		}
+
+		// Abandon through the actor of the game, so a command that just touched it keeps it alive
+		abandoned, err := controllers.UpdateGame(ctx, dbClient, gameID, func(ctx context.Context, game *model.Game) error {
+			if game.Status() != status || game.IdleFor(now) < timeout {
+				return errNotIdle
+			}
+			game.Abandon()
+			return nil
+		})
+		if errors.Is(err, errNotIdle) {
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Janitor failed to abandon game", logging.KeyGameID, gameID, logging.KeyError, err)
+			continue
+		}
+		games[gameID] = abandoned
+		report.Abandoned++
+	}
+
+	for _, game := range games {
+		if game == nil {
+			continue
+		}
+		switch game.Status() {
+		case model.StatusLobby:
+			report.Lobbies++
+		case model.StatusInProgress:
+			report.InProgress++
+		}
+	}
+
+	// Keep the lobby browser in line with what the sweep changed
+	if _, err := controllers.ReconcileLobbyIndex(ctx, dbClient, games); err != nil {
+		slog.ErrorContext(ctx, "Janitor failed to reconcile lobby index", logging.KeyError, err)
+	}
+
+	// Stop the actors of games that are finished or no longer stored
+	for _, gameID := range controllers.GameActorIDs() {
+		if game, ok := games[gameID]; ok && game != nil && !game.GameOver {
+			continue
+		}
+		if err := controllers.StopGameActor(ctx, gameID); err != nil {
+			slog.ErrorContext(ctx, "Janitor failed to stop game actor", logging.KeyGameID, gameID, logging.KeyError, err)
+			continue
+		}
+		report.Evicted++
+	}
+
+	// Expired invites cannot be redeemed anymore, so their redemption records are no longer needed
+	deleted, err := invite.DeleteExpired(ctx, dbClient, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "Janitor failed to delete expired invites", logging.KeyError, err)
+	}
+	report.Invites = deleted
+
+	return report, nil
+}
+
+// archive moves a finished game from the games tree to archive/games
+func archive(ctx context.Context, dbClient *db.Client, gameID string, game *model.Game) error {
+	if err := dbClient.NewRef("archive/games/"+gameID).Set(ctx, game); err != nil {
+		return err
+	}
+	if err := dbClient.NewRef("games/" + gameID).Delete(ctx); err != nil {
+		return err
+	}
+	if game.HasPassword {
+		return controllers.DeleteLobbyPassword(ctx, dbClient, gameID)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package lcr
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 )
@@ -13,6 +14,8 @@ type LCRGame struct {
 	Player   *LCRPlayer
 	Winner   *LCRPlayer
 	GameOver bool
+	// Turns is the number of turns played so far
+	Turns int
 }
 
 func NewLCRGame(players []*LCRPlayer, rollResults []int) *LCRGame {
@@ -29,14 +32,18 @@ func NewLCRGame(players []*LCRPlayer, rollResults []int) *LCRGame {
 
 const minPlayers = 3
 
+// ErrNotEnoughPlayers is returned when a game has fewer than the minimum number of players
+var ErrNotEnoughPlayers = errors.New("not enough players")
+
 func (g *LCRGame) Play() error {
 	if len(g.Players) < minPlayers {
-		return fmt.Errorf("not enough players to start the game, minimum required: %d", minPlayers)
+		return fmt.Errorf("%w to start the game, minimum required: %d", ErrNotEnoughPlayers, minPlayers)
 	}
 
 	for !g.GameOver {
 		g.Player = g.Players[g.Turn]
 		g.Player.TakeTurn(g)
+		g.Turns++
 		g.Turn++
 		if g.Turn == len(g.Players) {
 			g.Turn = 0
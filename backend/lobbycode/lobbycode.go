@@ -0,0 +1,201 @@
+package lobbycode
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"firebase.google.com/go/v4/db"
+)
+
+// Alphabet is the set of characters used by character codes. It leaves out 0/O and 1/I, which are easily confused.
+const Alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Code styles
+const (
+	// StyleChars codes are Length characters from Alphabet, e.g. "K7RQ2M"
+	StyleChars = "chars"
+	// StyleWords codes are Length words joined by dashes, e.g. "TIGER-MAPLE-SOCK"
+	StyleWords = "words"
+)
+
+// reservationsPath is the RTDB node mapping each reserved code to the ID of the game holding it
+const reservationsPath = "lobbyCodes"
+
+// maxAttempts bounds the number of codes tried before giving up
+const maxAttempts = 20
+
+// ErrExhausted is returned when no free code could be found
+var ErrExhausted = errors.New("no free lobby code found")
+
+// Config selects the shape of generated codes. A zero Length picks 6 characters or 3 words.
+type Config struct {
+	Style  string
+	Length int
+}
+
+// withDefaults fills in the default length of the style when none is set
+func (c Config) withDefaults() Config {
+	if c.Length == 0 {
+		c.Length = 6
+		if c.Style == StyleWords {
+			c.Length = 3
+		}
+	}
+	return c
+}
+
+// Validate checks that the config can produce enough distinct codes
+func (c Config) Validate() error {
+	switch c.Style {
+	case StyleChars:
+		if c.Length < 4 || c.Length > 12 {
+			return fmt.Errorf("lobby code length must be between 4 and 12 characters")
+		}
+	case StyleWords:
+		if c.Length < 2 || c.Length > 6 {
+			return fmt.Errorf("lobby code length must be between 2 and 6 words")
+		}
+	default:
+		return fmt.Errorf("lobby code style must be %s or %s", StyleChars, StyleWords)
+	}
+	return nil
+}
+
+// Service hands out lobby codes that are unique among active games and takes them back once a game is finished
+type Service struct {
+	dbClient *db.Client
+	cfg      Config
+	// isActive reports whether the game holding a code is still active
+	isActive func(ctx context.Context, gameID string) (bool, error)
+}
+
+// NewService creates a lobby code service. isActive is used to recycle codes still reserved by finished or deleted games.
+func NewService(dbClient *db.Client, cfg Config, isActive func(ctx context.Context, gameID string) (bool, error)) (*Service, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Service{dbClient: dbClient, cfg: cfg, isActive: isActive}, nil
+}
+
+// Generate returns a random code without reserving it
+func (s *Service) Generate() (string, error) {
+	if s.cfg.Style == StyleWords {
+		parts := make([]string, s.cfg.Length)
+		for i := range parts {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(words))))
+			if err != nil {
+				return "", err
+			}
+			parts[i] = words[n.Int64()]
+		}
+		return strings.Join(parts, "-"), nil
+	}
+
+	b := make([]byte, s.cfg.Length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(Alphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = Alphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// Reserve generates a code and reserves it for the game. Codes held by games that are no longer active are reused.
+func (s *Service) Reserve(ctx context.Context, gameID string) (string, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		code, err := s.Generate()
+		if err != nil {
+			return "", err
+		}
+
+		ok, err := s.claim(ctx, code, gameID)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return code, nil
+		}
+	}
+	return "", ErrExhausted
+}
+
+// Adopt reserves a code that was handed out before reservations existed. It reports false if another
+// active game already holds the code.
+func (s *Service) Adopt(ctx context.Context, code, gameID string) (bool, error) {
+	return s.claim(ctx, Normalize(code), gameID)
+}
+
+// claim reserves code for gameID unless an active game already holds it
+func (s *Service) claim(ctx context.Context, code, gameID string) (bool, error) {
+	ref := s.dbClient.NewRef(reservationsPath + "/" + code)
+
+	var holder string
+	if err := ref.Get(ctx, &holder); err != nil {
+		return false, err
+	}
+	if holder != "" && holder != gameID {
+		active, err := s.isActive(ctx, holder)
+		if err != nil {
+			return false, err
+		}
+		if active {
+			return false, nil
+		}
+	}
+
+	// Only take the code if nobody grabbed it since we looked
+	claimed := false
+	err := ref.Transaction(ctx, func(node db.TransactionNode) (interface{}, error) {
+		var current string
+		if err := node.Unmarshal(&current); err != nil {
+			return nil, err
+		}
+		claimed = current == "" || current == holder
+		if !claimed {
+			return current, nil
+		}
+		return gameID, nil
+	})
+	return claimed, err
+}
+
+// Release frees the code held by the game so it can be handed out again
+func (s *Service) Release(ctx context.Context, code, gameID string) error {
+	if code == "" {
+		return nil
+	}
+	ref := s.dbClient.NewRef(reservationsPath + "/" + Normalize(code))
+	return ref.Transaction(ctx, func(node db.TransactionNode) (interface{}, error) {
+		var current string
+		if err := node.Unmarshal(&current); err != nil {
+			return nil, err
+		}
+		if current == "" || current == gameID {
+			return nil, nil
+		}
+		return current, nil
+	})
+}
+
+// Holder returns the ID of the game holding the code, empty if the code is not reserved
+func (s *Service) Holder(ctx context.Context, code string) (string, error) {
+	var holder string
+	if err := s.dbClient.NewRef(reservationsPath+"/"+Normalize(code)).Get(ctx, &holder); err != nil {
+		return "", err
+	}
+	return holder, nil
+}
+
+// Normalize turns user input into the canonical form of a code: upper case, without surrounding
+// spaces, and with spaces or underscores between words replaced by dashes
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "-", "_", "-").Replace(code)
+}
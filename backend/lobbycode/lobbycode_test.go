@@ -0,0 +1,82 @@
+package lobbycode
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "K7QM2X", want: "K7QM2X"},
+		{code: "  k7qm2x ", want: "K7QM2X"},
+		{code: "brave-otter-sock", want: "BRAVE-OTTER-SOCK"},
+		{code: "brave otter sock", want: "BRAVE-OTTER-SOCK"},
+		{code: "Brave_Otter_Sock", want: "BRAVE-OTTER-SOCK"},
+		{code: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.code); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "default chars", cfg: Config{Style: StyleChars}},
+		{name: "default words", cfg: Config{Style: StyleWords}},
+		{name: "short chars", cfg: Config{Style: StyleChars, Length: 3}, wantErr: true},
+		{name: "long chars", cfg: Config{Style: StyleChars, Length: 13}, wantErr: true},
+		{name: "one word", cfg: Config{Style: StyleWords, Length: 1}, wantErr: true},
+		{name: "unknown style", cfg: Config{Style: "emoji"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.withDefaults().Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		cfg       Config
+		wantParts int
+		wantLen   int
+	}{
+		{cfg: Config{Style: StyleChars}, wantParts: 1, wantLen: 6},
+		{cfg: Config{Style: StyleChars, Length: 8}, wantParts: 1, wantLen: 8},
+		{cfg: Config{Style: StyleWords}, wantParts: 3},
+		{cfg: Config{Style: StyleWords, Length: 2}, wantParts: 2},
+	}
+	for _, tt := range tests {
+		s, err := NewService(nil, tt.cfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := s.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Normalize(code) != code {
+			t.Errorf("%+v: code %q is not normalized", tt.cfg, code)
+		}
+		if parts := strings.Split(code, "-"); len(parts) != tt.wantParts {
+			t.Errorf("%+v: code %q has %d parts, want %d", tt.cfg, code, len(parts), tt.wantParts)
+		}
+		if tt.wantLen != 0 && len(code) != tt.wantLen {
+			t.Errorf("%+v: code %q has length %d, want %d", tt.cfg, code, len(code), tt.wantLen)
+		}
+		if tt.cfg.Style == StyleChars && strings.Trim(code, Alphabet) != "" {
+			t.Errorf("%+v: code %q uses characters outside the alphabet", tt.cfg, code)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package lobbycode
+
+// words is the list word codes are drawn from: short, common and hard to mishear
+var words = []string{
+	"ACORN", "AMBER", "ANCHOR", "APPLE", "ARROW", "ATLAS", "BADGE", "BAGEL", "BAMBOO", "BANJO",
+	"BARN", "BASIL", "BEACH", "BEAR", "BEAVER", "BELL", "BERRY", "BIRCH", "BISON", "BLAZE", "BLOOM",
+	"BOAT", "BONGO", "BOOT", "BRAVE", "BREAD", "BRICK", "BROOK", "BUBBLE", "BUCKET", "BUFFALO",
+	"BUTTON", "CABIN", "CACTUS", "CAMEL", "CANDLE", "CANYON", "CARGO", "CARROT", "CASTLE", "CEDAR",
+	"CHALK", "CHERRY", "CHESS", "CHIMNEY", "CIDER", "CLOUD", "CLOVER", "COCOA", "COMET", "CORAL",
+	"COTTON", "COYOTE", "CRANE", "CRAYON", "CRICKET", "CROWN", "CUPCAKE", "DAISY", "DELTA", "DENIM",
+	"DESERT", "DIAMOND", "DINGO", "DOLPHIN", "DONUT", "DRAGON", "DRUM", "EAGLE", "EMBER", "EMERALD",
+	"FALCON", "FEATHER", "FERN", "FIDDLE", "FIG", "FJORD", "FLAME", "FLUTE", "FOREST", "FOSSIL",
+	"FOX", "GALAXY", "GARDEN", "GECKO", "GEYSER", "GINGER", "GIRAFFE", "GLACIER", "GLOBE", "GOBLIN",
+	"GRAPE", "GRAVY", "GUITAR", "HAMMER", "HARBOR", "HAZEL", "HEDGE", "HELMET", "HERON", "HIPPO",
+	"HONEY", "HOOK", "HORIZON", "IGLOO", "ISLAND", "IVORY", "JACKET", "JAGUAR", "JASMINE", "JELLY",
+	"JIGSAW", "JUNGLE", "KAYAK", "KETTLE", "KIWI", "KOALA", "LADDER", "LAGOON", "LANTERN", "LASSO",
+	"LEMON", "LEOPARD", "LILAC", "LIMBO", "LLAMA", "LOBSTER", "LOTUS", "MAGNET", "MANGO", "MAPLE",
+	"MARBLE", "MEADOW", "MELON", "MINT", "MITTEN", "MOOSE", "MOSAIC", "MUFFIN", "MUSTARD", "NACHO",
+	"NEBULA", "NECTAR", "NOODLE", "NUTMEG", "OASIS", "OCEAN", "OLIVE", "ORBIT", "ORCHID", "OTTER",
+	"OWL", "PADDLE", "PANDA", "PAPAYA", "PARROT", "PEACH", "PEBBLE", "PEPPER", "PICKLE", "PILLOW",
+	"PIRATE", "PIZZA", "PLANET", "PLUM", "POCKET", "POPCORN", "PRAIRIE", "PRETZEL", "PUFFIN",
+	"PUMPKIN", "QUARTZ", "QUILL", "RABBIT", "RADISH", "RAINBOW", "RAVEN", "REEF", "RIVER", "ROBIN",
+	"ROCKET", "SADDLE", "SALMON", "SANDAL", "SAPPHIRE", "SCARF", "SHADOW", "SHELL", "SHERPA",
+	"SIERRA", "SOCK", "SPARROW", "SPICE", "SPONGE", "SPRUCE", "SQUID", "STAR", "STORM", "SUMMIT",
+	"SUNSET", "SWAN", "TACO", "TANGO", "TEAPOT", "THUNDER", "TIGER", "TOFFEE", "TOMATO", "TORCH",
+	"TOUCAN", "TRUMPET", "TULIP", "TUNDRA", "TURTLE", "TWIG", "UMBRELLA", "VALLEY", "VELVET",
+	"VIOLIN", "VOLCANO", "WAFFLE", "WALNUT", "WALRUS", "WHALE", "WILLOW", "WIZARD", "YETI", "YOGURT",
+	"ZEBRA", "ZEPHYR", "ZIGZAG",
+}
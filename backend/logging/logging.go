@@ -0,0 +1,194 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Attribute keys shared by every log line
+const (
+	KeyRequestID = "requestId"
+	KeyGameID    = "gameId"
+	KeyUserID    = "userId"
+	KeyIP        = "ip"
+	KeyError     = "error"
+	KeyTraceID   = "traceId"
+)
+
+// redacted replaces the value of secret attributes
+const redacted = "[REDACTED]"
+
+// secretKeys are attributes whose value is never logged
+var secretKeys = map[string]bool{
+	"authorization": true,
+	"token":         true,
+	"password":      true,
+	"secret":        true,
+	"credentials":   true,
+}
+
+// identifierKeys are attributes identifying a person. They are logged as a short keyed hash so log lines of
+// the same user can still be correlated, while the identifiers cannot be recovered by hashing guesses.
+var identifierKeys = map[string]bool{
+	KeyUserID:  true,
+	KeyIP:      true,
+	"uid":      true,
+	"adminUid": true,
+	"email":    true,
+}
+
+// Config selects the format and the minimum level of the logs
+type Config struct {
+	Level  string
+	Format string
+	// HashKey keys the hashes of identifiers. When empty a random key is used, so the hashes of one user
+	// only match within a run of the server.
+	HashKey []byte
+}
+
+// ParseLevel turns debug, info, warn or error into a slog level
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return l, fmt.Errorf("log level must be debug, info, warn or error")
+	}
+	return l, nil
+}
+
+// New creates a logger writing to w that redacts secrets and hashes identifiers
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	key := cfg.HashKey
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate log hash key: %w", err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactor(key)}
+
+	switch cfg.Format {
+	case FormatJSON, "":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("log format must be %s or %s", FormatJSON, FormatText)
+}
+
+// redactor returns a function hiding secret attributes and hashing identifiers with key
+func redactor(key []byte) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		k := strings.ToLower(a.Key)
+		switch {
+		case secretKeys[k]:
+			return slog.String(a.Key, redacted)
+		case identifierKeys[a.Key] || identifierKeys[k]:
+			if value := a.Value.String(); value != "" {
+				return slog.String(a.Key, hash(key, value))
+			}
+		}
+		return a
+	}
+}
+
+// hash returns a short HMAC of an identifier, to correlate log lines without logging the identifier
+func hash(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// FromCtx returns the default logger annotated with the request ID, trace ID and user ID of the request
+func FromCtx(c *fiber.Ctx) *slog.Logger {
+	logger := slog.Default()
+	if requestID, ok := c.Locals(KeyRequestID).(string); ok && requestID != "" {
+		logger = logger.With(KeyRequestID, requestID)
+	}
+	if spanContext := trace.SpanContextFromContext(c.UserContext()); spanContext.HasTraceID() {
+		logger = logger.With(KeyTraceID, spanContext.TraceID().String())
+	}
+	if userID, ok := c.Locals("user").(string); ok && userID != "" {
+		logger = logger.With(KeyUserID, userID)
+	}
+	return logger
+}
+
+// Middleware logs every request once it has been handled. Errors returned by the handlers are written
+// by the app's error handler here, so the logged status is the one sent and middlewares registered before
+// this one see the final response. skip leaves out requests that should not be logged, such as health probes.
+func Middleware(skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+
+		start := time.Now()
+		err := c.Next()
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		status := c.Response().StatusCode()
+
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		path := c.Path()
+		lobbyCode := c.Params("lobbyCode")
+		if strings.Contains(lobbyCode, ".") {
+			// Invite tokens are accepted in place of lobby codes and must not end up in the logs
+			path = strings.Replace(path, lobbyCode, redacted, 1)
+			lobbyCode = redacted
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Method()),
+			slog.String("path", path),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String(KeyIP, c.IP()),
+		}
+		if lobbyCode != "" {
+			attrs = append(attrs, slog.String("lobbyCode", lobbyCode))
+		}
+		gameID := c.Params("gameID")
+		if id, ok := c.Locals("gameID").(string); ok && gameID == "" {
+			gameID = id
+		}
+		if gameID != "" {
+			attrs = append(attrs, slog.String(KeyGameID, gameID))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String(KeyError, err.Error()))
+		}
+		FromCtx(c).LogAttrs(c.UserContext(), level, "request", attrs...)
+		return nil
+	}
+}
@@ -1,26 +1,14 @@
 package main
 
 import (
-	"backend/controllers"
-	"backend/db"
-	"backend/routes"
-	"backend/util"
-
-	_ "github.com/lib/pq"
-
-	"log"
-	"math/rand"
+	"fmt"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 
-	_ "backend/docs"
+	"backend/logging"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/fiber/v2/middleware/recover"
+	_ "backend/docs"
 )
 
 // @title LCR API Documentation
@@ -33,45 +21,56 @@ import (
 // @host localhost:3000
 // @BasePath /
 
-func main() {
-
-	util.LoadEnv()
-
-	db.Init()
-
-	// Create a channel to listen for OS signals
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		sig := <-sigs
-		log.Printf("Received signal: %s", sig)
-		log.Println("Shutting down...")
-		db.ClosePgConnection()
-		os.Exit(0)
-	}()
-
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	app := fiber.New(fiber.Config{
-		ErrorHandler: controllers.ErrorHandler(),
-	})
+// command is a subcommand of the binary. Each one sets up only the stores and services it uses.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
 
-	app.Use(recover.New())
-	app.Use(logger.New()) // <-- Add this line to use the Logger middleware.
+var commands = []command{
+	{"serve", "run the API server (the default command)", serve},
+	{"migrate", "apply the pending schema and data migrations", runMigrations},
+	{"simulate", "play games offline with the LCR engine and print statistics", simulate},
+	{"export", "write games to an archive file", exportGames},
+	{"import", "load games from an archive file", importGames},
+	{"admin", "run a maintenance task, see admin -h", admin},
+}
 
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: "http://localhost:5173,https://lcr.up.railway.app",
-		AllowMethods: "GET,POST,PUT",
-		AllowHeaders: "Origin, Content-Type, Accept, Bearer, Authorization",
-	}))
+func main() {
+	args := os.Args[1:]
+	// Without a command, or with only flags, the server is started as before commands existed
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		serve(args)
+		return
+	}
 
-	routes.GameRoutes(app)
-	routes.SwaggerRoutes(app)
-	routes.NotFoundRoute(app)
-	routes.StaticRoutes(app)
+	name, args := args[0], args[1:]
+	if name == "help" {
+		usage()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(args)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "backend: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
 
-	if err := app.Listen("0.0.0.0:3000"); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+func usage() {
+	fmt.Fprint(os.Stderr, "Usage: backend <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprint(os.Stderr, "\nRun backend <command> -h for the flags of a command.\n")
+}
+
+// fatal logs an error that keeps the command from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, logging.KeyError, err)
+	os.Exit(1)
 }
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "lcr"
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	storeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_request_duration_seconds",
+		Help:      "Latency of Firebase RTDB calls by operation and top-level node.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "node", "status"})
+
+	activeLobbies = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_lobbies",
+		Help:      "Lobbies waiting for players, as of the last cleanup sweep.",
+	})
+
+	gamesInProgress = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "games_in_progress",
+		Help:      "Games that have started and are not over, as of the last cleanup sweep.",
+	})
+
+	turnsPlayed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "turns_played_total",
+		Help:      "Turns played across all games.",
+	})
+
+	gamesFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "games_finished_total",
+		Help:      "Games won by a player, by number of players.",
+	}, []string{"players"})
+
+	gamesWon = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "games_won_total",
+		Help:      "Games won, by whether the winner was a bot or a human.",
+	}, []string{"winner"})
+
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected with 429 by the rate limiter, by route class.",
+	}, []string{"class"})
+)
+
+// Middleware records the latency of every request under its route template, so /games/abc and /games/xyz
+// share a series. It must be registered before the logging middleware, which writes errors into the response.
+// skip leaves out requests that should not be measured, such as health probes.
+func Middleware(skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+
+		route := c.Route().Path
+		if status == fiber.StatusNotFound && route == "/" {
+			// Unmatched paths end up in the catch-all handler, don't let them create a series each
+			route = "unmatched"
+		}
+		requestDuration.WithLabelValues(c.Method(), route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// SetGameCounts records the number of open lobbies and games in progress
+func SetGameCounts(lobbies, inProgress int) {
+	activeLobbies.Set(float64(lobbies))
+	gamesInProgress.Set(float64(inProgress))
+}
+
+// TurnPlayed counts a played turn
+func TurnPlayed() {
+	turnsPlayed.Inc()
+}
+
+// GameFinished counts a game that was won
+func GameFinished(players int, botWon bool) {
+	gamesFinished.WithLabelValues(strconv.Itoa(players)).Inc()
+	winner := "human"
+	if botWon {
+		winner = "bot"
+	}
+	gamesWon.WithLabelValues(winner).Inc()
+}
+
+// ObserveStoreRequest records the latency of an RTDB request
+func ObserveStoreRequest(operation, node, status string, d time.Duration) {
+	storeDuration.WithLabelValues(operation, node, status).Observe(d.Seconds())
+}
+
+// RateLimited counts a request rejected by the rate limit of a route class
+func RateLimited(class string) {
+	rateLimited.WithLabelValues(class).Inc()
+}
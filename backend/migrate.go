@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"backend/db"
+	"backend/migrate"
+)
+
+// runMigrations applies the pending migrations. PostgreSQL migrations run first, as the Firebase credentials
+// may be read from a table they create.
+func runMigrations(args []string) {
+	fs := flag.NewFlagSet("backend migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only list the pending migrations")
+	store := fs.String("store", "all", "store to migrate: postgres, rtdb or all")
+	cfg := setup(fs, args)
+	switch *store {
+	case "all", migrate.Postgres, migrate.RTDB:
+	default:
+		fatal("Invalid flag", fmt.Errorf("-store must be postgres, rtdb or all, got %q", *store))
+	}
+
+	ctx := context.Background()
+	stores := &migrate.Stores{}
+	if (*store == "all" && cfg.UsesPostgres()) || *store == migrate.Postgres {
+		if err := db.OpenPostgres(cfg); err != nil {
+			fatal("Failed to connect to PostgreSQL", err)
+		}
+		defer db.ClosePgConnection()
+		stores.Postgres = db.PgDb
+		applyMigrations(ctx, stores, *dryRun)
+	}
+	if *store == "all" || *store == migrate.RTDB {
+		connectStores(cfg)
+		stores = &migrate.Stores{RTDB: db.DbClient}
+		applyMigrations(ctx, stores, *dryRun)
+	}
+}
+
+// applyMigrations applies or lists the pending migrations of stores
+func applyMigrations(ctx context.Context, stores *migrate.Stores, dryRun bool) {
+	if dryRun {
+		pending, err := migrate.Pending(ctx, stores)
+		if err != nil {
+			fatal("Failed to list migrations", err)
+		}
+		for _, m := range pending {
+			fmt.Printf("%s\t%s\t%s\n", m.ID, m.Store, m.Description)
+		}
+		return
+	}
+
+	ran, err := migrate.Run(ctx, stores, func(m migrate.Migration) {
+		slog.Info("Applying migration", "id", m.ID, "store", m.Store, "description", m.Description)
+	})
+	if err != nil {
+		fatal("Migration failed", err)
+	}
+	slog.Info("Migrations applied", "count", len(ran))
+}
@@ -0,0 +1,148 @@
+// Package migrate applies the schema and data migrations of the stores, each once.
+//
+// Applied PostgreSQL migrations are recorded in the schema_migrations table, applied RTDB migrations
+// in the meta/migrations node.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"firebase.google.com/go/v4/db"
+)
+
+// Stores a migration can run against
+const (
+	Postgres = "postgres"
+	RTDB     = "rtdb"
+)
+
+// rtdbMigrationsPath is the RTDB node recording the applied RTDB migrations, keyed by ID
+const rtdbMigrationsPath = "meta/migrations"
+
+// Migration is one change to a store. IDs are applied in order.
+type Migration struct {
+	ID          string
+	Description string
+	Store       string
+	Up          func(ctx context.Context, stores *Stores) error
+}
+
+// Stores are the connections migrations run against. A nil store is skipped.
+type Stores struct {
+	Postgres *sql.DB
+	RTDB     *db.Client
+}
+
+// applied records when a migration was applied
+type applied struct {
+	AppliedAt time.Time `json:"AppliedAt"`
+}
+
+// Pending returns the migrations of the connected stores that were not applied yet, in order
+func Pending(ctx context.Context, stores *Stores) ([]Migration, error) {
+	done, err := appliedIDs(ctx, stores)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range sorted() {
+		if !stores.has(m.Store) || done[m.ID] {
+			continue
+		}
+		pending = append(pending, m)
+	}
+	return pending, nil
+}
+
+// Run applies the pending migrations in order, calling before for each, and returns those applied.
+// It stops at the first that fails.
+func Run(ctx context.Context, stores *Stores, before func(Migration)) ([]Migration, error) {
+	pending, err := Pending(ctx, stores)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range pending {
+		if before != nil {
+			before(m)
+		}
+		if err := m.Up(ctx, stores); err != nil {
+			return ran, fmt.Errorf("migration %s failed: %w", m.ID, err)
+		}
+		if err := stores.record(ctx, m); err != nil {
+			return ran, fmt.Errorf("failed to record migration %s: %w", m.ID, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+func sorted() []Migration {
+	ms := append([]Migration(nil), migrations...)
+	sort.Slice(ms, func(i, j int) bool { return ms[i].ID < ms[j].ID })
+	return ms
+}
+
+func (s *Stores) has(store string) bool {
+	switch store {
+	case Postgres:
+		return s.Postgres != nil
+	case RTDB:
+		return s.RTDB != nil
+	}
+	return false
+}
+
+// appliedIDs returns the IDs of the migrations applied to the connected stores
+func appliedIDs(ctx context.Context, stores *Stores) (map[string]bool, error) {
+	done := make(map[string]bool)
+	if stores.Postgres != nil {
+		if _, err := stores.Postgres.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			id TEXT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL
+		)`); err != nil {
+			return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+		rows, err := stores.Postgres.QueryContext(ctx, "SELECT id FROM schema_migrations")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			done[id] = true
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if stores.RTDB != nil {
+		var records map[string]*applied
+		if err := stores.RTDB.NewRef(rtdbMigrationsPath).Get(ctx, &records); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rtdbMigrationsPath, err)
+		}
+		for id := range records {
+			done[id] = true
+		}
+	}
+	return done, nil
+}
+
+// record marks a migration applied in its store
+func (s *Stores) record(ctx context.Context, m Migration) error {
+	now := time.Now().UTC()
+	if m.Store == Postgres {
+		_, err := s.Postgres.ExecContext(ctx, "INSERT INTO schema_migrations (id, applied_at) VALUES ($1, $2)", m.ID, now)
+		return err
+	}
+	return s.RTDB.NewRef(rtdbMigrationsPath+"/"+m.ID).Set(ctx, &applied{AppliedAt: now})
+}
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/db"
+
+	"backend/controllers"
+	"backend/model"
+)
+
+// migrations are every migration of the stores. New ones get the next ID.
+var migrations = []Migration{
+	{
+		ID:          "0001_firebase_credentials",
+		Description: "create the firebase table holding the Firebase service account",
+		Store:       Postgres,
+		Up: func(ctx context.Context, stores *Stores) error {
+			_, err := stores.Postgres.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS firebase (credentials JSONB NOT NULL)")
+			return err
+		},
+	},
+	{
+		ID:          "0002_game_defaults",
+		Description: "store the timestamps and the settings defaults of games created before they existed",
+		Store:       RTDB,
+		Up:          backfillGames,
+	},
+	{
+		ID:          "0003_lobby_index",
+		Description: "build the lobbies index of the lobby browser from the games",
+		Store:       RTDB,
+		Up: func(ctx context.Context, stores *Stores) error {
+			games, err := loadGames(ctx, stores)
+			if err != nil {
+				return err
+			}
+			_, err = controllers.ReconcileLobbyIndex(ctx, stores.RTDB, games)
+			return err
+		},
+	},
+}
+
+// backfillGames stores the timestamps and settings of games where they are missing. Each game is written
+// in a transaction, so a change saved meanwhile by a running server is not overwritten.
+func backfillGames(ctx context.Context, stores *Stores) error {
+	games, err := loadGames(ctx, stores)
+	if err != nil {
+		return err
+	}
+	for gameID, game := range games {
+		if game == nil || !backfillGame(game) {
+			continue
+		}
+		err := stores.RTDB.NewRef("games/"+gameID).Transaction(ctx, func(node db.TransactionNode) (interface{}, error) {
+			var current *model.Game
+			if err := node.Unmarshal(&current); err != nil {
+				return nil, err
+			}
+			if current != nil {
+				backfillGame(current)
+			}
+			return current, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillGame fills in the timestamps and settings the game is missing, reporting whether it changed it
+func backfillGame(game *model.Game) bool {
+	settings := game.Settings.WithDefaults()
+	if !game.UpdatedAt.IsZero() && !game.CreatedAt.IsZero() && settings == game.Settings {
+		return false
+	}
+	game.Settings = settings
+	if game.UpdatedAt.IsZero() {
+		game.Touch()
+	}
+	if game.CreatedAt.IsZero() {
+		game.CreatedAt = game.UpdatedAt
+	}
+	return true
+}
+
+func loadGames(ctx context.Context, stores *Stores) (map[string]*model.Game, error) {
+	var games map[string]*model.Game
+	err := stores.RTDB.NewRef("games").Get(ctx, &games)
+	return games, err
+}
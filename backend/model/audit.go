@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// AuditEntry records a moderation action taken by an admin
+type AuditEntry struct {
+	Action   string    `json:"Action"`
+	GameID   string    `json:"GameID"`
+	AdminUID string    `json:"AdminUID"`
+	Reason   string    `json:"Reason,omitempty"`
+	Detail   string    `json:"Detail,omitempty"`
+	At       time.Time `json:"At"`
+}
@@ -4,6 +4,9 @@ import (
 	"math/rand"
 )
 
+// WildFace is the die face that counts as a wild in the wild rule set
+const WildFace = 1
+
 type Dice struct {
 	Sides int   `json:"Sides"`
 	Rolls []int `json:"Rolls,omitempty"`
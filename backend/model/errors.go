@@ -0,0 +1,40 @@
+package model
+
+import (
+	"errors"
+
+	"backend/lcr"
+)
+
+// Domain errors returned by games. Callers compare them with errors.Is; the returned errors wrap them
+// with details such as the player name.
+var (
+	// ErrGameNotFound is returned when no game matches an ID or lobby code
+	ErrGameNotFound = errors.New("game not found")
+	// ErrGameOver is returned for actions on a game that has ended
+	ErrGameOver = errors.New("game is over")
+	// ErrGameNotStarted is returned for turns taken in a lobby that has not been started
+	ErrGameNotStarted = errors.New("game has not started")
+	// ErrGameStarted is returned for lobby actions on a game that has already started
+	ErrGameStarted = errors.New("game has already started")
+	// ErrNotEnoughPlayers is returned when starting a game with fewer than MinPlayers players
+	ErrNotEnoughPlayers = lcr.ErrNotEnoughPlayers
+	// ErrPlayersNotReady is returned when starting a game before every player is ready
+	ErrPlayersNotReady = errors.New("not every player is ready")
+	// ErrNotYourTurn is returned when a player rolls out of turn
+	ErrNotYourTurn = errors.New("it is not your turn")
+	// ErrNotInGame is returned when a user acts on a game they have not joined
+	ErrNotInGame = errors.New("you are not a player in this game")
+	// ErrAlreadyInGame is returned when a user joins a lobby they already play in
+	ErrAlreadyInGame = errors.New("you are already a player in this game")
+	// ErrLobbyFull is returned when joining a lobby without free seats
+	ErrLobbyFull = errors.New("lobby is full")
+	// ErrPlayerNotFound is returned when a named player is not in the game
+	ErrPlayerNotFound = errors.New("player not found")
+	// ErrNameTaken is returned when joining with the name of a player already in the lobby
+	ErrNameTaken = errors.New("player name is already taken")
+	// ErrInvalidSettings is returned for lobby settings outside their limits
+	ErrInvalidSettings = errors.New("invalid settings")
+	// ErrInvalidTurn is returned when handing the turn to a seat that does not exist
+	ErrInvalidTurn = errors.New("invalid turn")
+)
@@ -3,68 +3,133 @@ package model
 import (
 	"backend/lcr"
 	"fmt"
+	"strings"
+	"time"
+)
+
+// Game statuses as reported by Status
+const (
+	StatusLobby      = "lobby"
+	StatusInProgress = "in_progress"
+	StatusOver       = "over"
+	StatusAbandoned  = "abandoned"
 )
 
 type Game struct {
-	Players   []*Player    `json:"Players"`
-	Creator   *Player      `json:"Creator,omitempty"`
-	Dice      *Dice        `json:"Dice,omitempty"`
-	Pot       int          `json:"Pot"`
-	Turn      int          `json:"Turn"`
-	Player    *Player      `json:"Player,omitempty"`
-	Winner    *Player      `json:"Winner,omitempty"`
-	GameOver  bool         `json:"GameOver"`
-	LobbyCode string       `json:"LobbyCode"`
-	LCRGame   *lcr.LCRGame `json:"LCRGame,omitempty"`
-	GameID    string       `json:"gameID,omitempty"`
-}
-
-// convertToLCRPlayers converts []*Player to []*lcr.LCRPlayer
-func convertToLCRPlayers(players []*Player) []*lcr.LCRPlayer {
-	lcrPlayers := make([]*lcr.LCRPlayer, len(players))
-	for i, player := range players {
-		lcrPlayers[i] = &lcr.LCRPlayer{
-			Name:  player.Name,
-			Chips: player.Chips,
-		}
-	}
-	return lcrPlayers
+	Players   []*Player     `json:"Players"`
+	Creator   *Player       `json:"Creator,omitempty"`
+	Dice      *Dice         `json:"Dice,omitempty"`
+	Pot       int           `json:"Pot"`
+	Turn      int           `json:"Turn"`
+	Player    *Player       `json:"Player,omitempty"`
+	Winner    *Player       `json:"Winner,omitempty"`
+	GameOver  bool          `json:"GameOver"`
+	LobbyCode string        `json:"LobbyCode"`
+	LCRGame   *lcr.LCRGame  `json:"LCRGame,omitempty"`
+	GameID    string        `json:"gameID,omitempty"`
+	Started   bool          `json:"Started"`
+	Abandoned bool          `json:"Abandoned,omitempty"`
+	History   []*TurnRecord `json:"History,omitempty"`
+	CreatedAt time.Time     `json:"CreatedAt"`
+	UpdatedAt time.Time     `json:"UpdatedAt"`
+	// Version goes up by one with every change, for conditional and long-polling reads
+	Version  int64    `json:"Version"`
+	Settings Settings `json:"Settings"`
+	// HasPassword is set when joining requires a password. The hash itself is kept out of the game.
+	HasPassword bool `json:"HasPassword,omitempty"`
 }
 
-// NewGame creates a new game instance
-func NewGame(players []*Player) (*Game, *lcr.LCRGame) {
-	// Initialize player chips to 3
+// TurnRecord is a single entry in the turn history of a game
+type TurnRecord struct {
+	Turn   int       `json:"Turn"`
+	Player string    `json:"Player"`
+	Rolls  []int     `json:"Rolls"`
+	Pot    int       `json:"Pot"`
+	At     time.Time `json:"At"`
+}
+
+// NewGame creates a new game instance with the given lobby settings
+func NewGame(players []*Player, settings Settings) *Game {
+	settings = settings.WithDefaults()
+
+	// Initialize player chips to the starting chips
 	for _, player := range players {
-		player.Chips = 3
+		player.Chips = settings.StartingChips
 	}
 
 	dice := NewDice()
 
-	// Initialize LCRGame
-	lcrPlayers := convertToLCRPlayers(players)
-	lcrGame := lcr.NewLCRGame(lcrPlayers, nil)
-
+	now := time.Now().UTC()
 	game := &Game{
-		Players:  players,
-		Dice:     dice,
-		Pot:      0,
-		Turn:     0,
-		Player:   players[0],
-		Winner:   nil,
-		GameOver: false,
+		Players:   players,
+		Dice:      dice,
+		Pot:       0,
+		Turn:      0,
+		Player:    players[0],
+		Winner:    nil,
+		GameOver:  false,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+		Settings:  settings,
 	}
-	return game, lcrGame
+	return game
 }
 
 // PlayTurn plays a turn in the game
 func (g *Game) PlayTurn() {
+	g.Started = true
 	g.Player = g.Players[g.Turn]
 	g.Dice.Rolls = g.Player.TakeTurnWithoutInput(g) // Update to store the dice roll results
+	g.History = append(g.History, &TurnRecord{
+		Turn:   g.Turn,
+		Player: g.Player.Name,
+		Rolls:  g.Dice.Rolls,
+		Pot:    g.Pot,
+		At:     time.Now().UTC(),
+	})
 	g.Turn++
 	if g.Turn == len(g.Players) {
 		g.Turn = 0
 	}
 
+	if g.rolledThreeWilds() {
+		g.GameOver = true
+		g.Winner = g.Player
+		return
+	}
+	g.checkWinner()
+}
+
+// rolledThreeWilds reports whether the last roll was three wilds in a wild game
+func (g *Game) rolledThreeWilds() bool {
+	if g.Settings.RuleSet != RuleSetWild || len(g.Dice.Rolls) != 3 {
+		return false
+	}
+	for _, roll := range g.Dice.Rolls {
+		if roll != WildFace {
+			return false
+		}
+	}
+	return true
+}
+
+// richestOpponent returns the player other than p holding the most chips, or nil if nobody else has any
+func (g *Game) richestOpponent(p *Player) *Player {
+	var richest *Player
+	for _, player := range g.Players {
+		if player == p || player.Chips == 0 {
+			continue
+		}
+		if richest == nil || player.Chips > richest.Chips {
+			richest = player
+		}
+	}
+	return richest
+}
+
+// checkWinner ends the game when only one player has chips left
+func (g *Game) checkWinner() {
 	remainingPlayers := 0
 	for _, player := range g.Players {
 		if player.Chips > 0 {
@@ -82,23 +147,227 @@ func (g *Game) PlayTurn() {
 	}
 }
 
-func (g *Game) Start() error {
-	if len(g.Players) < 3 {
-		return fmt.Errorf("not enough players to start the game, minimum required: 3")
+// Touch records that the game has just been modified and bumps its version. Games stored before
+// timestamps were introduced get their creation time set as well.
+func (g *Game) Touch() {
+	g.Version++
+	g.UpdatedAt = time.Now().UTC()
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = g.UpdatedAt
+	}
+}
+
+// IdleFor returns how long the game has gone without being modified
+func (g *Game) IdleFor(now time.Time) time.Duration {
+	return now.Sub(g.UpdatedAt)
+}
+
+// TurnDeadline returns when the current player runs out of time to roll, counted from the last change of
+// the game. It reports false when no turn timer runs, because the game is not in progress or has none.
+func (g *Game) TurnDeadline() (time.Time, bool) {
+	if !g.Started || g.GameOver || len(g.Players) == 0 || g.Settings.TurnTimeout <= 0 {
+		return time.Time{}, false
+	}
+	return g.UpdatedAt.Add(time.Duration(g.Settings.TurnTimeout) * time.Second), true
+}
+
+// Status reports which stage of its life the game is in
+func (g *Game) Status() string {
+	switch {
+	case g.Abandoned:
+		return StatusAbandoned
+	case g.GameOver:
+		return StatusOver
+	case g.Started:
+		return StatusInProgress
+	default:
+		return StatusLobby
+	}
+}
+
+// ForceEnd ends the game immediately, awarding the win to the player holding the most chips
+func (g *Game) ForceEnd() {
+	var winner *Player
+	for _, player := range g.Players {
+		if winner == nil || player.Chips > winner.Chips {
+			winner = player
+		}
+	}
+	g.Winner = winner
+	g.GameOver = true
+}
+
+// Abandon ends the game without a winner
+func (g *Game) Abandon() {
+	g.Winner = nil
+	g.GameOver = true
+	g.Abandoned = true
+}
+
+// RemovePlayer removes the named player from the game. Any chips they held go to the pot.
+func (g *Game) RemovePlayer(name string) error {
+	player := g.PlayerNamed(name)
+	index := -1
+	for i, p := range g.Players {
+		if p == player {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return fmt.Errorf("%w: %q", ErrPlayerNotFound, name)
+	}
+
+	g.Pot += g.Players[index].Chips
+	g.Players = append(g.Players[:index], g.Players[index+1:]...)
+
+	if index < g.Turn {
+		g.Turn--
+	}
+	if len(g.Players) == 0 {
+		g.Turn = 0
+		g.Player = nil
+		return nil
+	}
+	if g.Turn >= len(g.Players) {
+		g.Turn = 0
 	}
+	g.Player = g.Players[g.Turn]
+
+	if g.Started && !g.GameOver {
+		g.checkWinner()
+	}
+	return nil
+}
+
+// ResetTurn hands the turn to the player at the given index and clears the last roll
+func (g *Game) ResetTurn(turn int) error {
+	if turn < 0 || turn >= len(g.Players) {
+		return fmt.Errorf("%w: turn %d out of range, game has %d players", ErrInvalidTurn, turn, len(g.Players))
+	}
+	g.Turn = turn
+	g.Player = g.Players[turn]
+	if g.Dice == nil {
+		g.Dice = NewDice()
+	}
+	g.Dice.Rolls = []int{}
+	return nil
+}
+
+// HasUser reports whether the user plays in the game
+func (g *Game) HasUser(userID string) bool {
+	for _, player := range g.Players {
+		if player.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
 
-	// Convert Players to LCRPlayers
-	lcrPlayers := convertToLCRPlayers(g.Players)
+// IsHost reports whether the user hosts the game
+func (g *Game) IsHost(userID string) bool {
+	return g.Creator != nil && g.Creator.UserID != "" && g.Creator.UserID == userID
+}
 
-	// Initialize the Dice
-	g.Dice = &Dice{} // Initialize with default settings
+// CheckTurn reports whether the user may roll for the current player. Players roll their own turns and
+// the host rolls for the bots.
+func (g *Game) CheckTurn(userID string) error {
+	if g.GameOver {
+		return ErrGameOver
+	}
+	if !g.Started {
+		return ErrGameNotStarted
+	}
+	if len(g.Players) == 0 {
+		return fmt.Errorf("%w to play", ErrNotEnoughPlayers)
+	}
+	if !g.HasUser(userID) {
+		return ErrNotInGame
+	}
+	current := g.Players[g.Turn%len(g.Players)]
+	if current.IsBot() {
+		if g.IsHost(userID) {
+			return nil
+		}
+		return fmt.Errorf("%w, the host rolls for %s", ErrNotYourTurn, current.Name)
+	}
+	if current.UserID != "" && current.UserID == userID {
+		return nil
+	}
+	return fmt.Errorf("%w, %s is playing", ErrNotYourTurn, current.Name)
+}
+
+// HasPlayerNamed reports whether a player of the game has the given name, ignoring case and surrounding
+// spaces, so a new player cannot take a name that only looks different
+func (g *Game) HasPlayerNamed(name string) bool {
+	return g.PlayerNamed(name) != nil
+}
 
-	// Start the game
-	g.LCRGame = lcr.NewLCRGame(lcrPlayers, nil)
-	err := g.LCRGame.Play()
-	if err != nil {
+// PlayerNamed returns the player with the given name, nil if nobody in the game has it. Names are compared
+// without surrounding spaces and regardless of case, the way joining keeps them unique.
+func (g *Game) PlayerNamed(name string) *Player {
+	name = strings.TrimSpace(name)
+	for _, player := range g.Players {
+		if strings.EqualFold(strings.TrimSpace(player.Name), name) {
+			return player
+		}
+	}
+	return nil
+}
+
+// LeaveLobby removes the named player from a lobby that has not started. When the host leaves, the next
+// human player becomes host, and a lobby left without human players is abandoned.
+func (g *Game) LeaveLobby(name string) error {
+	if g.Started || g.GameOver {
+		return ErrGameStarted
+	}
+	player := g.PlayerNamed(name)
+	if player == nil {
+		return fmt.Errorf("%w: %q", ErrPlayerNotFound, name)
+	}
+
+	// Nobody has rolled yet, so the chips of the player leave with them instead of going to the pot
+	g.Pot -= player.Chips
+	if err := g.RemovePlayer(player.Name); err != nil {
 		return err
 	}
 
+	if g.Creator == nil || g.Creator.Name != player.Name {
+		return nil
+	}
+	g.Creator = nil
+	for _, p := range g.Players {
+		if !p.IsBot() {
+			g.Creator = p
+			break
+		}
+	}
+	if g.Creator == nil {
+		g.Abandon()
+	}
+	return nil
+}
+
+// Start closes the lobby and hands the first turn to the first player. Every player must be ready.
+func (g *Game) Start() error {
+	if g.Started || g.GameOver {
+		return ErrGameStarted
+	}
+	if len(g.Players) < MinPlayers {
+		return fmt.Errorf("%w to start the game, minimum required: %d", ErrNotEnoughPlayers, MinPlayers)
+	}
+	for _, player := range g.Players {
+		if !player.LobbyStatus {
+			return fmt.Errorf("%w: %s is not ready", ErrPlayersNotReady, player.Name)
+		}
+	}
+
+	if g.Dice == nil {
+		g.Dice = NewDice()
+	}
+	g.Turn = 0
+	g.Player = g.Players[0]
+	g.Started = true
+
 	return nil
 }
@@ -0,0 +1,225 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+// newTestGame returns a started game hosted by ann, with bob and a bot also playing and ann on turn
+func newTestGame() *Game {
+	ann := &Player{Name: "Ann", Chips: 3, LobbyStatus: true, UserID: "user-ann"}
+	bob := &Player{Name: "Bob", Chips: 3, LobbyStatus: true, UserID: "user-bob"}
+	bot := &Player{Name: "Bot 1", Chips: 3, LobbyStatus: true, UserID: BotUserID}
+	return &Game{
+		Players: []*Player{ann, bob, bot},
+		Creator: ann,
+		Player:  ann,
+		Started: true,
+	}
+}
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(g *Game)
+		wantErr error
+	}{
+		{name: "every player ready"},
+		{name: "player not ready", setup: func(g *Game) { g.Players[1].LobbyStatus = false }, wantErr: ErrPlayersNotReady},
+		{name: "too few players", setup: func(g *Game) { g.Players = g.Players[:2] }, wantErr: ErrNotEnoughPlayers},
+		{name: "already started", setup: func(g *Game) { g.Started = true }, wantErr: ErrGameStarted},
+		{name: "game over", setup: func(g *Game) { g.GameOver = true }, wantErr: ErrGameStarted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newTestGame()
+			game.Started = false
+			game.Turn = 2
+			game.Player = nil
+			game.Dice = nil
+			if tt.setup != nil {
+				tt.setup(game)
+			}
+
+			err := game.Start()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !game.Started || game.Turn != 0 || game.Player != game.Players[0] || game.Dice == nil {
+				t.Fatalf("got started %v turn %d player %v, want the first player on turn", game.Started, game.Turn, game.Player)
+			}
+		})
+	}
+}
+
+func TestCheckTurn(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(g *Game)
+		userID  string
+		wantErr error
+	}{
+		{name: "own turn", userID: "user-ann"},
+		{name: "turn of another player", userID: "user-bob", wantErr: ErrNotYourTurn},
+		{name: "not in the game", userID: "user-eve", wantErr: ErrNotInGame},
+		{
+			name:    "not started",
+			setup:   func(g *Game) { g.Started = false },
+			userID:  "user-ann",
+			wantErr: ErrGameNotStarted,
+		},
+		{
+			name:    "game over",
+			setup:   func(g *Game) { g.GameOver = true },
+			userID:  "user-ann",
+			wantErr: ErrGameOver,
+		},
+		{
+			name:   "host rolls for a bot",
+			setup:  func(g *Game) { g.Turn = 2 },
+			userID: "user-ann",
+		},
+		{
+			name:    "guest rolls for a bot",
+			setup:   func(g *Game) { g.Turn = 2 },
+			userID:  "user-bob",
+			wantErr: ErrNotYourTurn,
+		},
+		{
+			name: "bot turn without a host",
+			setup: func(g *Game) {
+				g.Turn = 2
+				g.Creator = nil
+			},
+			userID:  "user-ann",
+			wantErr: ErrNotYourTurn,
+		},
+		{
+			name: "player without a user ID",
+			setup: func(g *Game) {
+				g.Players[1].UserID = ""
+				g.Turn = 1
+			},
+			userID:  "",
+			wantErr: ErrNotYourTurn,
+		},
+		{
+			name:    "no players",
+			setup:   func(g *Game) { g.Players = nil },
+			userID:  "user-ann",
+			wantErr: ErrNotEnoughPlayers,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newTestGame()
+			if tt.setup != nil {
+				tt.setup(game)
+			}
+			if err := game.CheckTurn(tt.userID); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlayerNamed(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Bob", want: "Bob"},
+		{name: "bob", want: "Bob"},
+		{name: "  BOB\t", want: "Bob"},
+		{name: "Bo", want: ""},
+		{name: "", want: ""},
+	}
+
+	game := newTestGame()
+	for _, tt := range tests {
+		got := ""
+		if player := game.PlayerNamed(tt.name); player != nil {
+			got = player.Name
+		}
+		if got != tt.want {
+			t.Errorf("PlayerNamed(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if game.HasPlayerNamed(tt.name) != (tt.want != "") {
+			t.Errorf("HasPlayerNamed(%q) disagrees with PlayerNamed", tt.name)
+		}
+	}
+}
+
+// newTestLobby returns an open lobby hosted by ann, with a bot and then bob waiting
+func newTestLobby() *Game {
+	game := newTestGame()
+	game.Started = false
+	game.Players[1], game.Players[2] = game.Players[2], game.Players[1]
+	game.Pot = 5
+	return game
+}
+
+func TestLeaveLobby(t *testing.T) {
+	tests := []struct {
+		name          string
+		setup         func(g *Game)
+		leaver        string
+		wantErr       error
+		wantHost      string
+		wantAbandoned bool
+	}{
+		{name: "guest leaves", leaver: "Bob", wantHost: "Ann"},
+		{name: "host leaves to the next human", leaver: "Ann", wantHost: "Bob"},
+		{name: "name in another case with spaces", leaver: " aNN ", wantHost: "Bob"},
+		{
+			name:          "last human leaves",
+			setup:         func(g *Game) { g.Players = g.Players[:2] },
+			leaver:        "Ann",
+			wantAbandoned: true,
+		},
+		{
+			name:     "started game",
+			setup:    func(g *Game) { g.Started = true },
+			leaver:   "Bob",
+			wantErr:  ErrGameStarted,
+			wantHost: "Ann",
+		},
+		{name: "unknown player", leaver: "Eve", wantErr: ErrPlayerNotFound, wantHost: "Ann"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := newTestLobby()
+			if tt.setup != nil {
+				tt.setup(game)
+			}
+			players := len(game.Players)
+
+			err := game.LeaveLobby(tt.leaver)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (len(game.Players) != players-1 || game.PlayerNamed(tt.leaver) != nil) {
+				t.Errorf("%s is still in the game", tt.leaver)
+			}
+			if game.Pot != 5 {
+				t.Errorf("pot is %d, want it unchanged at 5", game.Pot)
+			}
+			if game.Abandoned != tt.wantAbandoned {
+				t.Errorf("abandoned is %v, want %v", game.Abandoned, tt.wantAbandoned)
+			}
+			host := ""
+			if game.Creator != nil {
+				host = game.Creator.Name
+			}
+			if host != tt.wantHost {
+				t.Errorf("host is %q, want %q", host, tt.wantHost)
+			}
+		})
+	}
+}
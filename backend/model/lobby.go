@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// LobbySummary is the entry kept for each open lobby in the lobby index
+type LobbySummary struct {
+	GameID      string    `json:"GameID"`
+	LobbyCode   string    `json:"LobbyCode"`
+	Creator     string    `json:"Creator,omitempty"`
+	Players     int       `json:"Players"`
+	MaxPlayers  int       `json:"MaxPlayers"`
+	RuleSet     string    `json:"RuleSet"`
+	HasPassword bool      `json:"HasPassword,omitempty"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	CreatedAtMs int64     `json:"CreatedAtMs"`
+}
+
+// FreeSeats returns the number of players that can still join the lobby
+func (l *LobbySummary) FreeSeats() int {
+	if free := l.MaxPlayers - l.Players; free > 0 {
+		return free
+	}
+	return 0
+}
+
+// IsOpenLobby reports whether the game is a lobby that can still be joined
+func (g *Game) IsOpenLobby() bool {
+	return g.Status() == StatusLobby
+}
+
+// IsBrowsable reports whether the game belongs in the lobby index
+func (g *Game) IsBrowsable() bool {
+	return g.IsOpenLobby() && g.IsListed()
+}
+
+// LobbySummary builds the lobby index entry for the game
+func (g *Game) LobbySummary(gameID string) *LobbySummary {
+	settings := g.Settings.WithDefaults()
+	summary := &LobbySummary{
+		GameID:      gameID,
+		LobbyCode:   g.LobbyCode,
+		Players:     len(g.Players),
+		MaxPlayers:  settings.MaxPlayers,
+		RuleSet:     settings.RuleSet,
+		HasPassword: g.HasPassword,
+		CreatedAt:   g.CreatedAt,
+		CreatedAtMs: g.CreatedAt.UnixMilli(),
+	}
+	if g.Creator != nil {
+		summary.Creator = g.Creator.Name
+	}
+	return summary
+}
@@ -4,6 +4,9 @@ import (
 	"backend/lcr"
 )
 
+// BotUserID is the user ID given to every bot player
+const BotUserID = "3XW4LgX0jMeo6mwTU9NrE0a2rYN2"
+
 // Player represents a game player
 type Player struct {
 	Name        string `json:"Name"`
@@ -21,6 +24,11 @@ func NewPlayer(name string) *Player {
 	}
 }
 
+// IsBot reports whether the player is a bot added to fill the lobby
+func (p *Player) IsBot() bool {
+	return p.UserID == BotUserID
+}
+
 // convertToLCRPlayers converts []*Player to []*lcr.LCRPlayer
 func ConvertToLCRPlayers(players []*Player) []*lcr.LCRPlayer {
 	lcrPlayers := make([]*lcr.LCRPlayer, len(players))
@@ -52,6 +60,12 @@ func (p *Player) TakeTurnWithoutInput(g *Game) []int {
 	rolls := g.Dice.Roll(numDice)
 
 	for _, roll := range rolls {
+		if roll == WildFace && g.Settings.RuleSet == RuleSetWild {
+			if opponent := g.richestOpponent(p); opponent != nil {
+				opponent.GiveChip(p)
+			}
+			continue
+		}
 		switch roll {
 		case 4:
 			p.GiveChip(g.Players[(g.Turn-1+len(g.Players))%len(g.Players)])
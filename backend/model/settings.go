@@ -0,0 +1,144 @@
+package model
+
+import "fmt"
+
+// Lobby visibilities
+const (
+	// VisibilityPublic lobbies are listed in the lobby browser
+	VisibilityPublic = "public"
+	// VisibilityUnlisted lobbies are hidden from the lobby browser but anyone with the lobby code can join
+	VisibilityUnlisted = "unlisted"
+	// VisibilityPrivate lobbies are hidden from the lobby browser and are joined with an invite, or with the lobby
+	// password if one is set. Without a password they are invite-only.
+	VisibilityPrivate = "private"
+)
+
+// Rule sets
+const (
+	// RuleSetClassic is the standard Left Center Right rule set
+	RuleSetClassic = "classic"
+	// RuleSetWild replaces the one-dot face with a wild: each wild steals a chip from the richest opponent,
+	// and rolling three wilds wins the game outright
+	RuleSetWild = "wild"
+)
+
+// Limits and defaults of the lobby settings
+const (
+	MinPlayers           = 3
+	DefaultMaxPlayers    = 8
+	MaxPlayersLimit      = 12
+	DefaultStartingChips = 3
+	MaxStartingChips     = 10
+	MaxTurnTimeout       = 600
+)
+
+// Settings holds the lobby settings chosen by the host of a game. The validate tags mirror Validate so that
+// requests get a field error per setting, zero values meaning the default.
+type Settings struct {
+	// MaxPlayers is the number of seats in the lobby, bots included
+	MaxPlayers int `json:"MaxPlayers,omitempty" validate:"omitempty,min=3,max=12"`
+	// RuleSet is the rule variant the game is played with
+	RuleSet string `json:"RuleSet,omitempty" validate:"oneof=classic wild"`
+	// StartingChips is the number of chips each player starts with
+	StartingChips int `json:"StartingChips,omitempty" validate:"omitempty,min=1,max=10"`
+	// TurnTimeout is the number of seconds a player has to roll before the server rolls for them, zero meaning no timer
+	TurnTimeout int    `json:"TurnTimeout,omitempty" validate:"min=0,max=600"`
+	Visibility  string `json:"Visibility,omitempty" validate:"oneof=public unlisted private"`
+	// BotFill makes adding bots fill every free seat instead of adding a random few
+	BotFill bool `json:"BotFill,omitempty"`
+}
+
+// WithDefaults returns a copy of the settings where every unset field has its default value.
+// Games created before a setting existed are read this way.
+func (s Settings) WithDefaults() Settings {
+	if s.MaxPlayers == 0 {
+		s.MaxPlayers = DefaultMaxPlayers
+	}
+	if s.RuleSet == "" {
+		s.RuleSet = RuleSetClassic
+	}
+	if s.StartingChips == 0 {
+		s.StartingChips = DefaultStartingChips
+	}
+	if s.Visibility == "" {
+		s.Visibility = VisibilityPublic
+	}
+	return s
+}
+
+// Validate checks that every setting is within its limits
+func (s Settings) Validate() error {
+	if s.MaxPlayers < MinPlayers || s.MaxPlayers > MaxPlayersLimit {
+		return fmt.Errorf("%w: MaxPlayers must be between %d and %d", ErrInvalidSettings, MinPlayers, MaxPlayersLimit)
+	}
+	if !IsValidRuleSet(s.RuleSet) {
+		return fmt.Errorf("%w: RuleSet must be %s or %s", ErrInvalidSettings, RuleSetClassic, RuleSetWild)
+	}
+	if s.StartingChips < 1 || s.StartingChips > MaxStartingChips {
+		return fmt.Errorf("%w: StartingChips must be between 1 and %d", ErrInvalidSettings, MaxStartingChips)
+	}
+	if s.TurnTimeout < 0 || s.TurnTimeout > MaxTurnTimeout {
+		return fmt.Errorf("%w: TurnTimeout must be between 0 and %d seconds", ErrInvalidSettings, MaxTurnTimeout)
+	}
+	if !IsValidVisibility(s.Visibility) {
+		return fmt.Errorf("%w: Visibility must be %s, %s or %s", ErrInvalidSettings, VisibilityPublic, VisibilityUnlisted, VisibilityPrivate)
+	}
+	return nil
+}
+
+// IsValidVisibility reports whether v is one of the known lobby visibilities
+func IsValidVisibility(v string) bool {
+	switch v {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// IsValidRuleSet reports whether r is one of the known rule sets
+func IsValidRuleSet(r string) bool {
+	switch r {
+	case RuleSetClassic, RuleSetWild:
+		return true
+	}
+	return false
+}
+
+// Visibility returns the visibility of the game, treating games created before visibilities existed as public
+func (g *Game) Visibility() string {
+	return g.Settings.WithDefaults().Visibility
+}
+
+// IsListed reports whether the game may appear in the lobby browser
+func (g *Game) IsListed() bool {
+	return g.Visibility() == VisibilityPublic
+}
+
+// FreeSeats returns the number of players that can still join the game
+func (g *Game) FreeSeats() int {
+	if free := g.Settings.WithDefaults().MaxPlayers - len(g.Players); free > 0 {
+		return free
+	}
+	return 0
+}
+
+// ApplySettings replaces the settings of a game that has not started yet. Every player's chips are
+// reset to the new starting chips.
+func (g *Game) ApplySettings(settings Settings) error {
+	if g.Started || g.GameOver {
+		return fmt.Errorf("%w, settings cannot be changed", ErrGameStarted)
+	}
+	settings = settings.WithDefaults()
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	if len(g.Players) > settings.MaxPlayers {
+		return fmt.Errorf("%w: MaxPlayers cannot be lower than the %d players already in the lobby", ErrInvalidSettings, len(g.Players))
+	}
+
+	g.Settings = settings
+	for _, player := range g.Players {
+		player.Chips = settings.StartingChips
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package ratelimit limits how often each user or client IP may call a class of routes, with token
+// buckets kept in memory.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend/metrics"
+	"backend/responses"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Limit lets Requests requests through per Per on average, in bursts of up to Burst requests
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// rate is the number of tokens added to a bucket per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the state of a bucket after taking a request from it
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, zero if it is now
+	RetryAfter time.Duration
+}
+
+// bucket holds the tokens of one key, as of updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter applies a limit to each key separately. It is safe for concurrent use, but not shared
+// between server instances.
+type Limiter struct {
+	class string
+	limit Limit
+
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	lastPurged time.Time
+}
+
+// New creates a limiter of a class of routes, named in metrics and error messages
+func New(class string, limit Limit) *Limiter {
+	return &Limiter{class: class, limit: limit, buckets: map[string]*bucket{}, lastPurged: time.Now()}
+}
+
+// Allow takes a token from the bucket of key, if it has one
+func (l *Limiter) Allow(key string, now time.Time) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.purge(now)
+
+	rate := l.limit.rate()
+	burst := float64(l.limit.Burst)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := Result{Allowed: b.tokens >= 1}
+	if result.Allowed {
+		b.tokens--
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((burst - b.tokens) / rate)
+	return result
+}
+
+// purge drops the buckets that have filled up again, at most once per minute. l.mu must be held.
+func (l *Limiter) purge(now time.Time) {
+	if now.Sub(l.lastPurged) < time.Minute {
+		return
+	}
+	l.lastPurged = now
+	rate := l.limit.rate()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*rate >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Middleware rejects the requests over the limit with 429. Every response gets the RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers, and rejected ones Retry-After.
+// key returns the bucket of a request, e.g. its user or IP.
+func (l *Limiter) Middleware(key func(c *fiber.Ctx) string) fiber.Handler {
+	window := int(math.Ceil(float64(l.limit.Burst) / l.limit.rate()))
+	policy := fmt.Sprintf("%d;w=%d", l.limit.Burst, window)
+	return func(c *fiber.Ctx) error {
+		result := l.Allow(key(c), time.Now())
+		c.Set("RateLimit-Limit", strconv.Itoa(l.limit.Burst))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		c.Set("RateLimit-Policy", policy)
+		if result.Allowed {
+			return c.Next()
+		}
+
+		metrics.RateLimited(l.class)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		return responses.NewError(fiber.StatusTooManyRequests, "too_many_requests",
+			fmt.Sprintf("Too many %s requests, retry in %ds", l.class, ceilSeconds(result.RetryAfter)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
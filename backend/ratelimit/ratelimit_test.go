@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// One token a second, in bursts of up to 3
+	limit := Limit{Requests: 60, Per: time.Minute, Burst: 3}
+
+	type step struct {
+		// after is the time of the request since start
+		after         time.Duration
+		key           string
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "burst then rejected",
+			steps: []step{
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 2},
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 1},
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 0},
+				{after: 0, key: "a", wantAllowed: false, wantRemaining: 0, wantRetry: time.Second},
+			},
+		},
+		{
+			name: "refills over time",
+			steps: []step{
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 2},
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 1},
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 0},
+				{after: 500 * time.Millisecond, key: "a", wantAllowed: false, wantRemaining: 0, wantRetry: 500 * time.Millisecond},
+				{after: time.Second, key: "a", wantAllowed: true, wantRemaining: 0},
+			},
+		},
+		{
+			name: "never above burst",
+			steps: []step{
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 2},
+				{after: time.Hour, key: "a", wantAllowed: true, wantRemaining: 2},
+			},
+		},
+		{
+			name: "keys have their own buckets",
+			steps: []step{
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 2},
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 1},
+				{after: 0, key: "a", wantAllowed: true, wantRemaining: 0},
+				{after: 0, key: "b", wantAllowed: true, wantRemaining: 2},
+				{after: 0, key: "a", wantAllowed: false, wantRemaining: 0, wantRetry: time.Second},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New("test", limit)
+			for i, s := range tt.steps {
+				got := l.Allow(s.key, start.Add(s.after))
+				if got.Allowed != s.wantAllowed || got.Remaining != s.wantRemaining || got.RetryAfter != s.wantRetry {
+					t.Fatalf("step %d: got %+v, want allowed %v remaining %d retry after %s",
+						i, got, s.wantAllowed, s.wantRemaining, s.wantRetry)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowReset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New("test", Limit{Requests: 60, Per: time.Minute, Burst: 3})
+	l.Allow("a", now)
+	got := l.Allow("a", now)
+	if got.Reset != 2*time.Second {
+		t.Fatalf("bucket resets in %s, want 2s", got.Reset)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New("test", Limit{Requests: 60, Per: time.Minute, Burst: 3})
+	l.lastPurged = now
+	l.Allow("a", now)
+	l.Allow("b", now.Add(59*time.Second))
+	for i := 0; i < 3; i++ {
+		l.Allow("c", now.Add(59*time.Second))
+	}
+
+	// A minute later a and b have filled up again and are dropped, c is still refilling
+	l.Allow("d", now.Add(61*time.Second))
+	for key, want := range map[string]bool{"a": false, "b": false, "c": true, "d": true} {
+		if _, ok := l.buckets[key]; ok != want {
+			t.Errorf("bucket %s kept: %v, want %v", key, ok, want)
+		}
+	}
+}
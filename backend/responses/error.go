@@ -4,35 +4,85 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// ErrorResponse is a struct for error response
+// Generic error codes, used when an error carries no more specific code
+const (
+	CodeBadRequest      = "bad_request"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeTooManyRequests = "too_many_requests"
+	CodeInternal        = "internal_error"
+	CodeUnavailable     = "unavailable"
+)
+
+// ErrorResponse is the body of every error response
 type ErrorResponse struct {
-	Code     int         `json:"code,omitempty"`
+	// Code is the HTTP status of the response
+	Code int `json:"code,omitempty"`
+	// Error is a machine-readable code such as game_over, stable across message changes
+	Error    string      `json:"error,omitempty"`
 	Message  string      `json:"message,omitempty"`
 	Internal string      `json:"internal,omitempty"`
 	Data     interface{} `json:"data,omitempty"`
+	// RequestID identifies the request in the server logs
+	RequestID string `json:"requestId,omitempty"`
+	// TraceID identifies the trace of the request when tracing is enabled
+	TraceID string `json:"traceId,omitempty"`
+	// Fields lists the invalid parts of the request when validation failed
+	Fields []FieldError `json:"fields,omitempty"`
 }
 
-// Define an error handling middleware
-func ErrorHandler(c *fiber.Ctx, err error) error {
-	// Cast error to fiber.Error to retrieve details
-	e, ok := err.(*fiber.Error)
-
-	if ok {
-		c.Status(e.Code)
-		return c.JSON(&ErrorResponse{
-			Code:     e.Code,
-			Message:  e.Message,
-			Internal: e.Error(),
-			Data:     nil,
-		})
-	}
+// FieldError describes one invalid part of a request
+type FieldError struct {
+	// In is where the field was sent: body, path, query or header
+	In string `json:"in"`
+	// Field is the JSON path of a body field, or the name of a param or header
+	Field string `json:"field"`
+	// Rule is the validation rule that failed, such as required or max
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error is an error with an HTTP status and a machine-readable code. Handlers return it when no
+// domain error describes the failure.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Data    interface{}
+	Fields  []FieldError
+}
 
-	// Fallback to status 500 and a generic error message if the error is not a fiber.Error
-	c.Status(500)
-	return c.JSON(&ErrorResponse{
-		Code:     500,
-		Message:  "An unexpected error occurred",
-		Internal: err.Error(),
-		Data:     nil,
-	})
+// NewError creates an error with the given status, code and message
+func NewError(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CodeForStatus returns the generic code of an HTTP status
+func CodeForStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return CodeBadRequest
+	case fiber.StatusUnauthorized:
+		return CodeUnauthorized
+	case fiber.StatusForbidden:
+		return CodeForbidden
+	case fiber.StatusNotFound:
+		return CodeNotFound
+	case fiber.StatusConflict:
+		return CodeConflict
+	case fiber.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case fiber.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= fiber.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
 }
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"backend/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminRoutes are the game moderation routes
+var AdminRoutes = []Route{
+	{
+		Method: fiber.MethodGet, Path: "/admin/games", Access: Admin,
+		Query: map[string]string{
+			"limit":  "int,min=1",
+			"status": "oneof=lobby in_progress over abandoned",
+		},
+		Handler: controllers.AdminListGames,
+	},
+	{
+		Method: fiber.MethodGet, Path: "/admin/games/:gameID", Access: Admin,
+		Handler: controllers.AdminGetGame,
+	},
+	{
+		Method: fiber.MethodPost, Path: "/admin/games/:gameID/force-end", Access: Admin, JSONBody: true,
+		Handler: controllers.AdminForceEndGame,
+	},
+	{
+		Method: fiber.MethodPost, Path: "/admin/games/:gameID/abandon", Access: Admin, JSONBody: true,
+		Handler: controllers.AdminAbandonGame,
+	},
+	{
+		Method: fiber.MethodDelete, Path: "/admin/games/:gameID/players/:playerName", Access: Admin, JSONBody: true,
+		Handler: controllers.AdminRemovePlayer,
+	},
+	{
+		Method: fiber.MethodPost, Path: "/admin/games/:gameID/reset-turn", Access: Admin, JSONBody: true,
+		Handler: controllers.AdminResetTurn,
+	},
+	{
+		Method: fiber.MethodGet, Path: "/admin/audit", Access: Admin,
+		Handler: controllers.AdminListAudit,
+	},
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"backend/controllers"
+	"backend/db"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// readinessTimeout bounds each readiness check so a hanging dependency cannot hang the probe
+const readinessTimeout = 3 * time.Second
+
+// probePaths are polled by the hosting platform and the metrics scraper, and kept out of the request log
+var probePaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/version": true,
+	"/metrics": true,
+}
+
+// HealthRoutes registers the liveness, readiness and version probes. They do not require authentication.
+func HealthRoutes(app *fiber.App) {
+	checks := []controllers.ReadinessCheck{
+		{Name: "store", Check: db.PingFirebase},
+		{Name: "auth", Check: db.PingAuth},
+		{Name: "shutdown", Check: func(ctx context.Context) error {
+			if controllers.IsShuttingDown() {
+				return errors.New("server is shutting down")
+			}
+			return nil
+		}},
+	}
+
+	app.Get("/healthz", controllers.Healthz)
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		return controllers.Readyz(c, checks, readinessTimeout)
+	})
+	app.Get("/version", controllers.Version)
+}
+
+// IsProbeRoute reports whether the request is a health probe or metrics scrape, for middlewares that should skip them
+func IsProbeRoute(c *fiber.Ctx) bool {
+	return probePaths[c.Path()]
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsRoutes registers the Prometheus scrape endpoint. It does not require authentication.
+func MetricsRoutes(app *fiber.App) {
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+}
@@ -1,7 +1,7 @@
 package routes
 
 import (
-	"net/http"
+	"backend/responses"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,12 +10,8 @@ func NotFoundRoute(app *fiber.App) {
 	// Register new special route.
 	app.Use(
 		func(c *fiber.Ctx) error {
-			// Return HTTP 404 status and JSON reponse.
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"status": http.StatusNotFound,
-				"error":  true,
-				"msg":    "sorry, the endpoint you are looking for does not exist",
-			})
+			// Return HTTP 404 through the error handler so it gets the common error body.
+			return responses.NewError(fiber.StatusNotFound, "route_not_found", "sorry, the endpoint you are looking for does not exist")
 		},
 	)
 }
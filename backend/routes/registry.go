@@ -0,0 +1,262 @@
+package routes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/controllers"
+	"backend/db"
+	"backend/idempotency"
+	"backend/ratelimit"
+	"backend/responses"
+	"backend/validate"
+
+	rtdb "firebase.google.com/go/v4/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIPrefix is the path every versioned route is registered under
+const APIPrefix = "/v1"
+
+// Access is who may call a route
+type Access int
+
+const (
+	// Public routes need no token
+	Public Access = iota
+	// User routes need a Firebase ID token
+	User
+	// Admin routes need a Firebase ID token of an admin
+	Admin
+)
+
+// Rate limit classes, each with its own limit per user, or per client IP on Public routes
+const (
+	RateRead   = "read"
+	RateWrite  = "write"
+	RateCreate = "create"
+	RatePublic = "public"
+)
+
+// Handler is a controller function, called with the Firebase RTDB client
+type Handler func(c *fiber.Ctx, dbClient *rtdb.Client) error
+
+// Route describes one API route. The registry adds authentication, timing and validation to it
+// the same way for every route. Request bodies are checked by the controllers with validate.Body.
+type Route struct {
+	Method string
+	// Path is relative to APIPrefix
+	Path   string
+	Access Access
+	// JSONBody rejects requests with a body that is not JSON
+	JSONBody bool
+	// Query and Headers map query params and headers to their validate rules
+	Query   map[string]string
+	Headers map[string]string
+	// RateClass picks the rate limit of the route. It defaults to RatePublic on Public routes, RateRead
+	// on GET routes and RateWrite on the others.
+	RateClass string
+	// Legacy are the unversioned paths the route was served on before, kept as deprecated aliases
+	Legacy  []string
+	Handler Handler
+}
+
+// ParamRules are the validate rules of the path params, the same on every route using them
+var ParamRules = map[string]string{
+	"gameID":     "required,max=64",
+	"lobbyCode":  "required,max=512",
+	"playerName": "required,max=32",
+}
+
+// Options configures the middleware a registry adds to its routes
+type Options struct {
+	// AdminUIDs may call Admin routes besides the users with the admin claim
+	AdminUIDs []string
+	// Idempotency replays the responses of POST and PUT requests sent again with the same
+	// Idempotency-Key. The header is ignored when it is nil.
+	Idempotency *idempotency.Store
+	// RateLimits holds the limiter of each rate class. Routes of a class without one are not limited.
+	RateLimits map[string]*ratelimit.Limiter
+}
+
+// Registry registers routes on an app under APIPrefix, and on their legacy paths
+type Registry struct {
+	routes []Route
+	opts   Options
+}
+
+// NewRegistry creates a registry adding the middleware configured by opts to its routes
+func NewRegistry(opts Options) *Registry {
+	return &Registry{opts: opts}
+}
+
+// Add adds routes to the registry
+func (r *Registry) Add(routes ...Route) {
+	r.routes = append(r.routes, routes...)
+}
+
+// Routes returns the routes added to the registry
+func (r *Registry) Routes() []Route {
+	return r.routes
+}
+
+// Prefixes returns APIPrefix and the first segment of every legacy path, e.g. /games, the paths
+// under which requests are for the API
+func (r *Registry) Prefixes() []string {
+	prefixes := []string{APIPrefix}
+	seen := map[string]bool{APIPrefix: true}
+	for _, route := range r.routes {
+		for _, legacy := range route.Legacy {
+			segment, _, _ := strings.Cut(strings.TrimPrefix(legacy, "/"), "/")
+			if prefix := "/" + segment; !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	return prefixes
+}
+
+// Mount registers every route of the registry on app
+func (r *Registry) Mount(app *fiber.App) {
+	api := app.Group(APIPrefix)
+	for _, route := range r.routes {
+		handlers := r.handlers(route)
+		api.Add(route.Method, route.Path, handlers...)
+		for _, legacy := range route.Legacy {
+			app.Add(route.Method, legacy, append([]fiber.Handler{deprecated(route)}, handlers...)...)
+		}
+	}
+}
+
+// handlers returns the middleware chain of a route, ending with its controller
+func (r *Registry) handlers(route Route) []fiber.Handler {
+	var handlers []fiber.Handler
+	switch route.Access {
+	case User:
+		handlers = append(handlers, controllers.AuthRequired())
+	case Admin:
+		handlers = append(handlers, controllers.AuthRequired(), controllers.AdminRequired(r.opts.AdminUIDs))
+	}
+	// After the token check, so users are limited by UID rather than by the IP they share
+	if limiter, ok := r.opts.RateLimits[rateClass(route)]; ok {
+		handlers = append(handlers, limiter.Middleware(requester))
+	}
+	idempotent := r.opts.Idempotency != nil && (route.Method == fiber.MethodPost || route.Method == fiber.MethodPut)
+	if idempotent {
+		route = withHeaderRule(route, idempotency.Header, idempotency.KeyRules)
+	}
+	handlers = append(handlers, validated(route))
+	if route.JSONBody {
+		handlers = append(handlers, requireJSON)
+	}
+	if idempotent {
+		handlers = append(handlers, r.opts.Idempotency.Middleware(requester))
+	}
+	return append(handlers, timed(route.Handler))
+}
+
+// validated checks the path params, query params and headers of a request against their rules
+func validated(route Route) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var fields []responses.FieldError
+		for _, param := range c.Route().Params {
+			if rules, ok := ParamRules[param]; ok {
+				if fe := validate.Var(validate.InPath, param, c.Params(param), rules); fe != nil {
+					fields = append(fields, *fe)
+				}
+			}
+		}
+		for _, name := range sortedKeys(route.Query) {
+			if fe := validate.Var(validate.InQuery, name, c.Query(name), route.Query[name]); fe != nil {
+				fields = append(fields, *fe)
+			}
+		}
+		for _, name := range sortedKeys(route.Headers) {
+			if fe := validate.Var(validate.InHeader, name, c.Get(name), route.Headers[name]); fe != nil {
+				fields = append(fields, *fe)
+			}
+		}
+		if err := validate.Failed(fields); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// rateClass returns the rate limit class of a route
+func rateClass(route Route) string {
+	switch {
+	case route.RateClass != "":
+		return route.RateClass
+	case route.Access == Public:
+		return RatePublic
+	case route.Method == fiber.MethodGet:
+		return RateRead
+	}
+	return RateWrite
+}
+
+// withHeaderRule returns a copy of route also checking the header name against rules, unless the
+// route has its own rules for it
+func withHeaderRule(route Route, name, rules string) Route {
+	if _, ok := route.Headers[name]; ok {
+		return route
+	}
+	headers := map[string]string{name: rules}
+	for header, headerRules := range route.Headers {
+		headers[header] = headerRules
+	}
+	route.Headers = headers
+	return route
+}
+
+// requester identifies who sent a request: the user, or the client IP on Public routes
+func requester(c *fiber.Ctx) string {
+	if userID, _ := c.Locals("user").(string); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + c.IP()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// timed calls the controller and reports how long it took in the Server-Timing header
+func timed(handler Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := handler(c, db.DbClient)
+		c.Set("Server-Timing", fmt.Sprintf("app;dur=%.1f", float64(time.Since(start).Microseconds())/1000))
+		return err
+	}
+}
+
+// requireJSON rejects a non-empty body sent with another content type than JSON
+func requireJSON(c *fiber.Ctx) error {
+	if len(c.Body()) > 0 && !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		return responses.NewError(fiber.StatusUnsupportedMediaType, "unsupported_media_type", "Request body must be JSON")
+	}
+	return c.Next()
+}
+
+// deprecated marks a response as served from a legacy path and links to the versioned one
+func deprecated(route Route) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		successor := APIPrefix + route.Path
+		for _, param := range c.Route().Params {
+			successor = strings.Replace(successor, ":"+param, c.Params(param), 1)
+		}
+		c.Set("Deprecation", "true")
+		c.Set(fiber.HeaderLink, fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		return c.Next()
+	}
+}
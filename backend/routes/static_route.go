@@ -1,10 +1,34 @@
 package routes
 
 import (
+	"io/fs"
+	"strings"
+
+	"backend/assets"
+
 	"github.com/gofiber/fiber/v2"
 )
 
-// SwaggerRoute func for descibe group of API Docs routes.
-func StaticRoutes(app *fiber.App) {
-	app.Static("/", "static")
+// nonAppPrefixes are served by the server itself, so missing paths under them get the JSON 404
+// instead of the frontend
+var nonAppPrefixes = []string{"/swagger", "/healthz", "/readyz", "/version", "/metrics"}
+
+// StaticRoutes serves the frontend files of files, and its index.html for the other paths that are
+// not API paths of api. It must be registered after every other route and before NotFoundRoute.
+func StaticRoutes(app *fiber.App, files fs.FS, api *Registry) error {
+	static, err := assets.New(files)
+	if err != nil {
+		return err
+	}
+
+	prefixes := append(api.Prefixes(), nonAppPrefixes...)
+	app.Use(static.Handler(func(path string) bool {
+		for _, prefix := range prefixes {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		}
+		return false
+	}))
+	return nil
 }
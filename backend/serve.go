@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"flag"
+	"io/fs"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"backend/config"
+	"backend/controllers"
+	"backend/db"
+	"backend/idempotency"
+	"backend/invite"
+	"backend/janitor"
+	"backend/logging"
+	"backend/metrics"
+	"backend/ratelimit"
+	"backend/routes"
+	"backend/tracing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// staticFiles is the frontend served next to the API, embedded so the server does not depend on its
+// working directory
+//
+//go:embed static
+var staticFiles embed.FS
+
+// serve runs the API server until it gets SIGINT or SIGTERM
+func serve(args []string) {
+	cfg := setup(flag.NewFlagSet("backend serve", flag.ExitOnError), args)
+
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
+	connectStores(cfg)
+
+	initLobbyCodes(cfg)
+
+	signer := invite.NewSigner([]byte(cfg.Invites.Secret))
+	if cfg.Invites.Secret == "" {
+		slog.Warn("INVITE_SECRET is not set, invites will not survive a restart")
+		if signer, err = invite.NewRandomSigner(); err != nil {
+			fatal("Failed to generate invite secret", err)
+		}
+	}
+	controllers.InitInvites(signer, cfg.Invites.BaseURL)
+
+	stopJanitor := janitor.Start(db.DbClient, janitor.Config{
+		Interval:         cfg.Janitor.Interval.Duration,
+		LobbyIdleTimeout: cfg.Janitor.LobbyIdleTimeout.Duration,
+		GameIdleTimeout:  cfg.Janitor.GameIdleTimeout.Duration,
+		Retention:        cfg.Janitor.Retention.Duration,
+	})
+
+	rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: controllers.ErrorHandler(),
+		ProxyHeader:  cfg.Server.ProxyHeader,
+	})
+
+	app.Use(recover.New())
+	app.Use(requestid.New(requestid.Config{ContextKey: logging.KeyRequestID}))
+	// Health probes and metrics scrapes run every few seconds and would drown out real requests. The logging
+	// middleware writes errors into the response, so tracing and metrics before it see the status sent.
+	app.Use(tracing.Middleware(routes.IsProbeRoute))
+	app.Use(metrics.Middleware(routes.IsProbeRoute))
+	app.Use(logging.Middleware(routes.IsProbeRoute))
+
+	app.Use(cors.New(cors.Config{
+		AllowOrigins: strings.Join(cfg.Server.AllowOrigins, ","),
+		AllowMethods: "GET,POST,PUT,DELETE",
+		AllowHeaders: "Origin, Content-Type, Accept, Bearer, Authorization, If-None-Match, Idempotency-Key",
+		// Lets the frontend read the version of a game, see replayed responses and back off when rate limited
+		ExposeHeaders: "ETag, Idempotent-Replayed, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy",
+	}))
+
+	routes.HealthRoutes(app)
+	routes.MetricsRoutes(app)
+	api := routes.NewRegistry(routes.Options{
+		AdminUIDs:   cfg.Admin.UIDs,
+		Idempotency: idempotency.NewStore(cfg.Idempotency.TTL.Duration),
+		RateLimits:  rateLimiters(cfg.RateLimits),
+	})
+	api.Add(routes.GameRoutes...)
+	api.Add(routes.AdminRoutes...)
+	api.Mount(app)
+	routes.SwaggerRoutes(app)
+	static, _ := fs.Sub(staticFiles, "static")
+	if err := routes.StaticRoutes(app, static, api); err != nil {
+		fatal("Failed to load static files", err)
+	}
+	routes.NotFoundRoute(app)
+
+	// Create a channel to listen for OS signals
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	// drained is closed once in-flight requests are done or the shutdown timeout passed
+	drained := make(chan struct{})
+	go func() {
+		sig := <-sigs
+		slog.Info("Shutting down", "signal", sig.String())
+		controllers.BeginShutdown()
+		if err := app.ShutdownWithTimeout(cfg.Server.ShutdownTimeout.Duration); err != nil {
+			slog.Error("Failed to drain in-flight requests", logging.KeyError, err)
+		}
+		close(drained)
+	}()
+
+	if err := app.Listen(cfg.Server.Addr); err != nil {
+		fatal("Failed to start server", err)
+	}
+	<-drained
+
+	stopJanitor()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := stopTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", logging.KeyError, err)
+	}
+
+	db.ClosePgConnection()
+	slog.Info("Shutdown complete")
+}
+
+// rateLimiters creates the limiter of each rate class of the routes, none if rate limits are disabled
+func rateLimiters(cfg config.RateLimits) map[string]*ratelimit.Limiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	limiters := map[string]*ratelimit.Limiter{}
+	for class, limit := range map[string]config.RateLimit{
+		routes.RateRead:   cfg.Read,
+		routes.RateWrite:  cfg.Write,
+		routes.RateCreate: cfg.Create,
+		routes.RatePublic: cfg.Public,
+	} {
+		limiters[class] = ratelimit.New(class, ratelimit.Limit{Requests: limit.Requests, Per: limit.Per.Duration, Burst: limit.Burst})
+	}
+	return limiters
+}
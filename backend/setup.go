@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"backend/config"
+	"backend/controllers"
+	"backend/db"
+	"backend/lobbycode"
+	"backend/logging"
+	"backend/util"
+)
+
+// setup loads the .env file and the configuration, parsing args with fs, and sets up logging
+func setup(fs *flag.FlagSet, args []string) *config.Config {
+	if err := util.LoadEnv(); err != nil {
+		fatal("Failed to load environment", err)
+	}
+
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+
+	logger, err := logging.New(os.Stdout, logging.Config{
+		Level:   cfg.Logging.Level,
+		Format:  cfg.Logging.Format,
+		HashKey: []byte(cfg.Logging.HashKey),
+	})
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
+	// Also routes the standard log package, used by libraries, through the structured logger
+	slog.SetDefault(logger)
+	if cfg.Logging.HashKey == "" {
+		slog.Warn("LOG_HASH_KEY is not set, hashed user IDs and IPs will change when the server restarts")
+	}
+	return cfg
+}
+
+// connectStores connects to Firebase, and to PostgreSQL if the Firebase credentials are read from there
+func connectStores(cfg *config.Config) {
+	if err := db.Init(cfg); err != nil {
+		fatal("Failed to connect to the stores", err)
+	}
+}
+
+// initLobbyCodes sets up the lobby code service. The stores must be connected.
+func initLobbyCodes(cfg *config.Config) {
+	lobbyCodes := lobbycode.Config{Style: cfg.LobbyCode.Style, Length: cfg.LobbyCode.Length}
+	if err := controllers.InitLobbyCodes(db.DbClient, lobbyCodes); err != nil {
+		fatal("Invalid configuration", fmt.Errorf("config: lobbyCode: %w", err))
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"backend/lcr"
+)
+
+// simulate plays games with the LCR engine, without any store, and prints how they went
+func simulate(args []string) {
+	fs := flag.NewFlagSet("backend simulate", flag.ExitOnError)
+	games := fs.Int("games", 1000, "number of games to play")
+	players := fs.Int("players", 4, "players per game")
+	chips := fs.Int("chips", 3, "chips each player starts with")
+	fs.Parse(args)
+	if *games < 1 || *players < 1 || *chips < 1 {
+		fmt.Fprintln(os.Stderr, "backend simulate: -games, -players and -chips must be positive")
+		os.Exit(2)
+	}
+
+	wins := make([]int, *players)
+	var turns, dice, pot, minTurns, maxTurns int
+	for i := 0; i < *games; i++ {
+		lcrPlayers := make([]*lcr.LCRPlayer, *players)
+		for seat := range lcrPlayers {
+			lcrPlayers[seat] = lcr.NewLCRPlayer(fmt.Sprintf("Player %d", seat+1))
+			lcrPlayers[seat].Chips = *chips
+		}
+		game := lcr.NewLCRGame(lcrPlayers, nil)
+		if err := game.Play(); err != nil {
+			fmt.Fprintln(os.Stderr, "backend simulate:", err)
+			os.Exit(1)
+		}
+
+		for seat, player := range lcrPlayers {
+			if player == game.Winner {
+				wins[seat]++
+			}
+		}
+		turns += game.Turns
+		dice += len(game.Dice.Rolls)
+		pot += game.Pot
+		if i == 0 || game.Turns < minTurns {
+			minTurns = game.Turns
+		}
+		if game.Turns > maxTurns {
+			maxTurns = game.Turns
+		}
+	}
+
+	n := float64(*games)
+	fmt.Printf("Played %d games of %d players with %d chips each\n\n", *games, *players, *chips)
+	fmt.Printf("Turns per game: %.1f on average, %d to %d\n", float64(turns)/n, minTurns, maxTurns)
+	fmt.Printf("Dice rolled per game: %.1f\n", float64(dice)/n)
+	fmt.Printf("Chips in the pot at the end: %.1f\n\n", float64(pot)/n)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SEAT\tWINS\tWIN RATE")
+	for seat, count := range wins {
+		fmt.Fprintf(w, "%d\t%d\t%.1f%%\n", seat+1, count, 100*float64(count)/n)
+	}
+	w.Flush()
+}
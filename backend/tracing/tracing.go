@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"backend/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	// ExporterNone disables tracing
+	ExporterNone = "none"
+	// ExporterStdout writes spans to stdout as JSON
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans to a file as JSON
+	ExporterFile = "file"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP
+	ExporterOTLP = "otlp"
+)
+
+// instrumentationName names the tracer every span of the server is created with
+const instrumentationName = "backend"
+
+// Config selects where spans are exported and how many traces are kept
+type Config struct {
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector address, e.g. localhost:4318. When empty the OTEL_EXPORTER_OTLP_*
+	// environment variables apply.
+	Endpoint string
+	// Insecure sends OTLP over plain HTTP
+	Insecure bool
+	// File is the path spans are appended to by the file exporter
+	File string
+	// SampleRatio is the fraction of new traces that are recorded, between 0 and 1
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider described by cfg. The returned function flushes and stops the
+// exporter and must be called on shutdown.
+func Setup(ctx context.Context, cfg Config) (shutdown func(ctx context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var closer io.Closer
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return func(ctx context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		var file *os.File
+		file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("lcr-backend"),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns the tracer of the server. It does nothing until Setup installs an exporter.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// TraceID returns the ID of the trace ctx belongs to, or an empty string when it is not traced
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// Middleware starts a span for every request, continuing the trace of an incoming traceparent header.
+// The span context is set as the user context of the request so handlers pass it on to the stores.
+// It must be registered before the logging middleware, which writes errors into the response.
+// skip leaves out requests that should not be traced, such as health probes.
+func Middleware(skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+
+		carrier := propagation.MapCarrier{}
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			carrier.Set(string(key), string(value))
+		})
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), carrier)
+
+		// The path and query are left out of the span, as they can carry lobby codes and invite tokens.
+		// The route template is recorded instead once routing is done.
+		ctx, span := Tracer().Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(c.Method()),
+				semconv.UserAgentOriginal(c.Get(fiber.HeaderUserAgent)),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// Name the span after the route template once routing is done, so spans of the same route group together
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+		if requestID, ok := c.Locals(logging.KeyRequestID).(string); ok {
+			span.SetAttributes(attribute.String("request.id", requestID))
+		}
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+		return err
+	}
+}
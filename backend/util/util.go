@@ -1,16 +1,17 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 
 	"github.com/joho/godotenv"
 )
 
-// LoadEnv loads the environment variables from the .env file and returns an ErrorResponse if an error occurs
-
+// LoadEnv loads the environment variables from the .env file, if there is one, and returns an error if it cannot be read
 func LoadEnv() error {
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return fmt.Errorf("error loading .env file: %w", err)
 	}
 	return nil
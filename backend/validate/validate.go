@@ -0,0 +1,264 @@
+// Package validate checks request bodies, params and headers against declarative rules.
+//
+// Rules are comma-separated, e.g. "required,max=32". Body structs declare them in a `validate` struct
+// tag, routes declare them per param and header.
+//
+//   - required: the value must be set, and strings must not be blank. Pointers that are nil and empty values
+//     skip the other rules otherwise.
+//   - min=N, max=N: bounds on numbers, on the length of strings in characters and on the length of slices.
+//   - maxbytes=N: a bound on the length of a string in bytes, for values such as bcrypt passwords.
+//   - int: a param or header must be an integer, min and max then bound its value.
+//   - oneof=a b: the value must be one of the space-separated words.
+//   - prefix=p: a param or header must start with p.
+//   - forbidden: the value must not be set by the client.
+//   - omitempty: a zero value skips the other rules, for fields where zero means the default.
+//
+// Struct fields and slice elements holding structs are checked recursively, and null slice elements
+// are rejected.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"backend/responses"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Where a checked value was sent
+const (
+	InBody   = "body"
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+)
+
+// CodeFailed is the error code of a request that failed validation
+const CodeFailed = "validation_failed"
+
+// Failed returns the error answered to a request with invalid fields, or nil if there are none
+func Failed(fields []responses.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	err := responses.NewError(fiber.StatusBadRequest, CodeFailed, "Request validation failed")
+	err.Fields = fields
+	return err
+}
+
+// Body parses the request body into v and checks it
+func Body(c *fiber.Ctx, v interface{}) error {
+	if err := c.BodyParser(v); err != nil {
+		return Failed([]responses.FieldError{{In: InBody, Rule: "json", Message: "must be a valid JSON body"}})
+	}
+	return Check(v)
+}
+
+// Check checks the fields of a parsed body against their validate tags
+func Check(v interface{}) error {
+	var fields []responses.FieldError
+	checkValue(reflect.ValueOf(v), "", &fields)
+	return Failed(fields)
+}
+
+// Var checks a param or header value against rules, returning nil if it is valid
+func Var(in, name, value, rules string) *responses.FieldError {
+	if strings.TrimSpace(value) == "" && hasRule(rules, "required") {
+		return &responses.FieldError{In: in, Field: name, Rule: "required", Message: "is required"}
+	}
+	if value == "" {
+		return nil
+	}
+
+	isInt := hasRule(rules, "int")
+	for _, rule := range strings.Split(rules, ",") {
+		rule, arg, _ := strings.Cut(rule, "=")
+		var msg string
+		switch rule {
+		case "int":
+			if _, err := strconv.Atoi(value); err != nil {
+				msg = "must be an integer"
+			}
+		case "min", "max":
+			if isInt {
+				n, err := strconv.Atoi(value)
+				if err == nil {
+					msg = bound(rule, arg, float64(n), "")
+				}
+			} else {
+				msg = bound(rule, arg, float64(utf8.RuneCountInString(value)), " characters")
+			}
+		case "maxbytes":
+			msg = bound("max", arg, float64(len(value)), " bytes")
+		case "oneof":
+			msg = oneOf(arg, value)
+		case "prefix":
+			if !strings.HasPrefix(value, arg) {
+				msg = fmt.Sprintf("must start with %q", arg)
+			}
+		case "forbidden":
+			msg = "must not be set"
+		}
+		if msg != "" {
+			return &responses.FieldError{In: in, Field: name, Rule: rule, Message: msg}
+		}
+	}
+	return nil
+}
+
+// checkValue checks the fields of structs reached from v, adding failures to fields
+func checkValue(v reflect.Value, path string, fields *[]responses.FieldError) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			value := v.Field(i)
+			if field.Anonymous {
+				checkValue(value, path, fields)
+				continue
+			}
+			fieldPath := joinPath(path, jsonName(field))
+			if rules := field.Tag.Get("validate"); rules != "" {
+				if fe := checkField(value, rules); fe != nil {
+					fe.In = InBody
+					fe.Field = fieldPath
+					*fields = append(*fields, *fe)
+					continue
+				}
+			}
+			checkValue(value, fieldPath, fields)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			if elem.Kind() == reflect.Pointer && elem.IsNil() {
+				*fields = append(*fields, responses.FieldError{In: InBody, Field: elemPath, Rule: "required", Message: "must not be null"})
+				continue
+			}
+			checkValue(elem, elemPath, fields)
+		}
+	}
+}
+
+// checkField checks a struct field against rules, returning the first that fails
+func checkField(v reflect.Value, rules string) *responses.FieldError {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if hasRule(rules, "required") {
+				return &responses.FieldError{Rule: "required", Message: "is required"}
+			}
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.IsZero() && hasRule(rules, "omitempty") {
+		return nil
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		var msg string
+		switch name {
+		case "required":
+			if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) ||
+				(v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+				msg = "is required"
+			}
+		case "min", "max":
+			switch v.Kind() {
+			case reflect.String:
+				msg = bound(name, arg, float64(utf8.RuneCountInString(v.String())), " characters")
+			case reflect.Slice, reflect.Array, reflect.Map:
+				msg = bound(name, arg, float64(v.Len()), " items")
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				msg = bound(name, arg, float64(v.Int()), "")
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				msg = bound(name, arg, float64(v.Uint()), "")
+			case reflect.Float32, reflect.Float64:
+				msg = bound(name, arg, v.Float(), "")
+			}
+		case "maxbytes":
+			if v.Kind() == reflect.String {
+				msg = bound("max", arg, float64(len(v.String())), " bytes")
+			}
+		case "oneof":
+			if v.Kind() == reflect.String && v.String() != "" {
+				msg = oneOf(arg, v.String())
+			}
+		case "forbidden":
+			if !v.IsZero() {
+				msg = "must not be set"
+			}
+		}
+		if msg != "" {
+			return &responses.FieldError{Rule: name, Message: msg}
+		}
+	}
+	return nil
+}
+
+// bound checks n against a min or max rule, returning the failure message
+func bound(rule, arg string, n float64, unit string) string {
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validate: invalid %s rule %q", rule, arg))
+	}
+	if rule == "min" && n < limit {
+		return fmt.Sprintf("must be at least %s%s", arg, unit)
+	}
+	if rule == "max" && n > limit {
+		return fmt.Sprintf("must be at most %s%s", arg, unit)
+	}
+	return ""
+}
+
+// oneOf checks value against a oneof rule, returning the failure message
+func oneOf(arg, value string) string {
+	options := strings.Fields(arg)
+	for _, option := range options {
+		if value == option {
+			return ""
+		}
+	}
+	return "must be one of " + strings.Join(options, ", ")
+}
+
+func hasRule(rules, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonName returns the name a struct field has in JSON
+func jsonName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,160 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+
+	"backend/responses"
+)
+
+type testPlayer struct {
+	Name  string `json:"Name" validate:"required,max=5"`
+	Ready bool   `json:"Ready" validate:"forbidden"`
+}
+
+type testBody struct {
+	Players  []*testPlayer `json:"Players" validate:"required,min=1"`
+	Password string        `json:"Password" validate:"maxbytes=4"`
+	Rules    string        `json:"Rules" validate:"oneof=classic wild"`
+	Seats    *int          `json:"Seats" validate:"min=3,max=12"`
+	Chips    int           `json:"Chips" validate:"omitempty,min=1,max=10"`
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// fieldErrors returns the field errors of err, failing the test if it is not a validation error
+func fieldErrors(t *testing.T, err error) []responses.FieldError {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var apiErr *responses.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeFailed {
+		t.Fatalf("got error %v, want a %s error", err, CodeFailed)
+	}
+	return apiErr.Fields
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		body *testBody
+		// want is the field and rule of each expected failure
+		want [][2]string
+	}{
+		{
+			name: "valid",
+			body: &testBody{Players: []*testPlayer{{Name: "Ann"}}, Password: "pass", Rules: "wild", Seats: intPtr(8)},
+		},
+		{
+			name: "missing players",
+			body: &testBody{},
+			want: [][2]string{{"Players", "required"}},
+		},
+		{
+			name: "blank name",
+			body: &testBody{Players: []*testPlayer{{Name: "  \t"}}},
+			want: [][2]string{{"Players[0].Name", "required"}},
+		},
+		{
+			name: "name too long in characters",
+			body: &testBody{Players: []*testPlayer{{Name: "Annabel"}}},
+			want: [][2]string{{"Players[0].Name", "max"}},
+		},
+		{
+			name: "multibyte name within characters",
+			body: &testBody{Players: []*testPlayer{{Name: "Zoë"}}},
+		},
+		{
+			name: "null player",
+			body: &testBody{Players: []*testPlayer{nil}},
+			want: [][2]string{{"Players[0]", "required"}},
+		},
+		{
+			name: "forbidden field set",
+			body: &testBody{Players: []*testPlayer{{Name: "Ann", Ready: true}}},
+			want: [][2]string{{"Players[0].Ready", "forbidden"}},
+		},
+		{
+			name: "password too long in bytes",
+			body: &testBody{Players: []*testPlayer{{Name: "Ann"}}, Password: "pässö"},
+			want: [][2]string{{"Password", "maxbytes"}},
+		},
+		{
+			name: "unknown rule set",
+			body: &testBody{Players: []*testPlayer{{Name: "Ann"}}, Rules: "house"},
+			want: [][2]string{{"Rules", "oneof"}},
+		},
+		{
+			name: "seats out of range",
+			body: &testBody{Players: []*testPlayer{{Name: "Ann"}}, Seats: intPtr(2)},
+			want: [][2]string{{"Seats", "min"}},
+		},
+		{
+			name: "zero chips meaning the default",
+			body: &testBody{Players: []*testPlayer{{Name: "Ann"}}, Chips: 0},
+		},
+		{
+			name: "chips out of range",
+			body: &testBody{Players: []*testPlayer{{Name: "Ann"}}, Chips: -1},
+			want: [][2]string{{"Chips", "min"}},
+		},
+		{
+			name: "several failures",
+			body: &testBody{Players: []*testPlayer{{Name: ""}}, Seats: intPtr(13)},
+			want: [][2]string{{"Players[0].Name", "required"}, {"Seats", "max"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := fieldErrors(t, Check(tt.body))
+			if len(fields) != len(tt.want) {
+				t.Fatalf("got %d field errors %+v, want %d", len(fields), fields, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if fields[i].Field != want[0] || fields[i].Rule != want[1] || fields[i].In != InBody {
+					t.Errorf("field error %d is %+v, want field %s rule %s", i, fields[i], want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestVar(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		rules    string
+		wantRule string
+	}{
+		{name: "empty optional", value: "", rules: "int,min=1"},
+		{name: "empty required", value: "", rules: "required", wantRule: "required"},
+		{name: "blank required", value: "  ", rules: "required", wantRule: "required"},
+		{name: "not an int", value: "ten", rules: "int", wantRule: "int"},
+		{name: "int below min", value: "0", rules: "int,min=1,max=100", wantRule: "min"},
+		{name: "int above max", value: "101", rules: "int,min=1,max=100", wantRule: "max"},
+		{name: "int in range", value: "100", rules: "int,min=1,max=100"},
+		{name: "string length", value: "abcdef", rules: "max=5", wantRule: "max"},
+		{name: "string bytes", value: "ééé", rules: "maxbytes=5", wantRule: "maxbytes"},
+		{name: "oneof", value: "middle", rules: "oneof=newest oldest", wantRule: "oneof"},
+		{name: "prefix", value: "Token abc", rules: "prefix=Bearer ", wantRule: "prefix"},
+		{name: "prefix ok", value: "Bearer abc", rules: "required,prefix=Bearer "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fe := Var(InQuery, "q", tt.value, tt.rules)
+			switch {
+			case tt.wantRule == "" && fe != nil:
+				t.Fatalf("got %+v, want no error", fe)
+			case tt.wantRule != "" && fe == nil:
+				t.Fatalf("got no error, want rule %s", tt.wantRule)
+			case fe != nil && (fe.Rule != tt.wantRule || fe.In != InQuery || fe.Field != "q"):
+				t.Fatalf("got %+v, want rule %s", fe, tt.wantRule)
+			}
+		})
+	}
+}